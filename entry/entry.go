@@ -16,7 +16,9 @@ import (
 	"github.com/machbase/neo-shell/internal"
 	"github.com/machbase/neo-shell/internal/machcli"
 	"github.com/machbase/neo-shell/internal/pretty"
+	"github.com/machbase/neo-shell/internal/profile"
 	"github.com/machbase/neo-shell/internal/session"
+	"github.com/machbase/neo-shell/internal/vfs"
 	"github.com/nyaosorg/go-readline-ny"
 	"golang.org/x/term"
 )
@@ -28,12 +30,25 @@ import (
 //     ex: neo-shell script.js arg1 arg2
 //  3. no args : start interactive shell
 //     ex: neo-shell
+//
+// Connection options:
+//   - --server, --user, --password : machbase-neo server and credentials
+//   - --profile name : use the named profile that is saved by 'profile save',
+//     the explicit options and -v, -e flags take precedence over the profile.
+//   - --tls, --ca-cert, --client-cert, --client-key, --insecure-skip-verify :
+//     connect to the server over TLS, the options are also taken from
+//     the NEOSHELL_TLS, NEOSHELL_CA_CERT ... environment variables (e.g. by -e or a profile).
+//   - --mach-tls : connect to the mach port over TLS as well (NEOSHELL_MACH_TLS),
+//     it requires a TLS terminator in front of the mach port.
+//   - -e NEOSHELL_QUERY_TIMEOUT=30s : the default timeout of each SQL statement.
 func Main(flags *flag.FlagSet, executable []string, args []string) {
 	var fstabs engine.FSTabs
 	var envVars engine.EnvVars = make(map[string]any)
 	var neoHost string
 	var neoUser string
 	var neoPassword string
+	var neoProfile string
+	var neoTLS session.TLSConfig
 	var err error
 
 	src := flags.String("C", "", "command to execute")
@@ -43,6 +58,13 @@ func Main(flags *flag.FlagSet, executable []string, args []string) {
 	flags.StringVar(&neoHost, "server", "", "machbase-neo host")
 	flags.StringVar(&neoUser, "user", "", "user name (default: sys)")
 	flags.StringVar(&neoPassword, "password", "", "password (default: manager)")
+	flags.StringVar(&neoProfile, "profile", "", "connection profile name")
+	flags.BoolVar(&neoTLS.Tls, "tls", false, "connect to the server over TLS")
+	flags.StringVar(&neoTLS.CaCert, "ca-cert", "", "CA certificate file to verify the server")
+	flags.StringVar(&neoTLS.ClientCert, "client-cert", "", "client certificate file (e.g. the one generated by 'key gen')")
+	flags.StringVar(&neoTLS.ClientKey, "client-key", "", "client private key file (default: <name>_key.pem of the client-cert)")
+	flags.BoolVar(&neoTLS.InsecureSkipVerify, "insecure-skip-verify", false, "skip verifying the server certificate")
+	flags.BoolVar(&neoTLS.MachTls, "mach-tls", false, "connect to the mach port over TLS (requires a TLS terminator)")
 	if err := flags.Parse(args); err != nil {
 		fmt.Println("Error parsing flags:", err.Error())
 		os.Exit(1)
@@ -62,8 +84,9 @@ func Main(flags *flag.FlagSet, executable []string, args []string) {
 			neoUser = user.(string)
 		}
 		if pass, ok := conf.Env["NEOSHELL_PASSWORD"]; ok {
-			neoPassword = pass.(engine.SecureString).Value()
+			neoPassword = profile.SecureValue(pass)
 		}
+		neoTLS.FromEnv(conf.Env)
 		if neoUser == "" {
 			neoUser, err = readLine("User", "SYS")
 			if err != nil {
@@ -81,6 +104,32 @@ func Main(flags *flag.FlagSet, executable []string, args []string) {
 			conf.Env["NEOSHELL_PASSWORD"] = engine.SecureString(neoPassword)
 		}
 	} else {
+		if neoProfile != "" {
+			p, err := profile.Load(neoProfile)
+			if err != nil {
+				fmt.Println("Error loading profile:", err.Error())
+				os.Exit(1)
+			}
+			if neoHost == "" {
+				neoHost = p.Host
+			}
+			if neoUser == "" {
+				neoUser = p.User
+			}
+			if neoPassword == "" {
+				neoPassword = p.Password
+			}
+			for _, tab := range p.Volumes {
+				if !fstabs.HasMountPoint(tab.MountPoint) {
+					fstabs = append(fstabs, tab)
+				}
+			}
+			for k, v := range p.Env {
+				if _, ok := envVars[k]; !ok {
+					envVars[k] = v
+				}
+			}
+		}
 		if neoHost == "" {
 			neoHost, err = readLine("Server", "127.0.0.1:5654")
 			if err != nil {
@@ -129,6 +178,8 @@ func Main(flags *flag.FlagSet, executable []string, args []string) {
 			"NEOSHELL_USER":     neoUser,
 			"NEOSHELL_PASSWORD": engine.SecureString(neoPassword),
 		}
+		neoTLS.FromEnv(envVars)
+		neoTLS.SetEnv(conf.Env)
 		conf.Aliases = map[string]string{
 			"describe": "show table",
 			"desc":     "show table",
@@ -148,6 +199,8 @@ func Main(flags *flag.FlagSet, executable []string, args []string) {
 		dirfs, _ := engine.DirFS(".")
 		conf.FSTabs = append(conf.FSTabs, engine.FSTab{MountPoint: "/work", FS: dirfs})
 	}
+	// native modules open the files by the paths of the scripts
+	vfs.Mount(conf.FSTabs)
 	// setup ExecBuilder to enable re-execution
 	conf.ExecBuilder = func(code string, args []string, env map[string]any) (*exec.Cmd, error) {
 		conf := engine.Config{
@@ -176,12 +229,14 @@ func Main(flags *flag.FlagSet, executable []string, args []string) {
 	eng.RegisterNativeModule("@jsh/session", session.Module)
 	eng.RegisterNativeModule("@jsh/machcli", machcli.Module)
 	eng.RegisterNativeModule("@jsh/pretty", pretty.Module)
+	eng.RegisterNativeModule("@jsh/profile", profile.Module)
 
 	// configure default session
 	if err := session.Configure(session.Config{
 		Server:   neoHost,
 		User:     neoUser,
 		Password: neoPassword,
+		TLS:      neoTLS,
 	}); err != nil {
 		fmt.Println("Error configuring session:", err.Error())
 		os.Exit(1)
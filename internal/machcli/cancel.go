@@ -0,0 +1,92 @@
+package machcli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/machbase/neo-server/v8/api/machcli"
+)
+
+// connSession is the server session of a connection, id is -1 if it is not found.
+type connSession struct {
+	server int
+	id     int64
+	looked bool
+}
+
+// Watch cancels the running statement of the connection on the server
+// when the context of the statement is done before it is closed.
+// The driver does not observe the context while it waits for the server,
+// so the statement is cancelled through another connection.
+func (db *Database) Watch(conn *machcli.Conn, qc *QueryContext) {
+	db.mu.Lock()
+	cs, ok := db.sessions[conn]
+	db.mu.Unlock()
+	if !ok {
+		return
+	}
+	if !cs.looked {
+		cs.id, cs.looked = sessionID(conn), true
+	}
+	if cs.id < 0 {
+		return
+	}
+	server, id := cs.server, cs.id
+	done, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-done:
+		case <-qc.Ctx.Done():
+			select {
+			case <-done: // closed already
+			default:
+				db.cancelSession(server, id)
+			}
+		}
+	}()
+	// the cancel in progress completes before the next statement starts
+	qc.stop = func() {
+		close(done)
+		<-exited
+	}
+}
+
+// Forget releases the session of the connection that is closed.
+func (db *Database) Forget(conn *machcli.Conn) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	delete(db.sessions, conn)
+}
+
+// sessionID returns the server session of the connection, it is -1 if it is not found.
+// The query finds the session by its own text that is unique.
+func sessionID(conn *machcli.Conn) int64 {
+	mark := fmt.Sprintf("neo-shell-%d-%p", time.Now().UnixNano(), conn)
+	row := conn.QueryRow(context.Background(), "SELECT SESS_ID FROM V$STMT WHERE QUERY LIKE '%"+mark+"%'")
+	var id int64
+	if err := row.Err(); err != nil {
+		return -1
+	}
+	if err := row.Scan(&id); err != nil {
+		return -1
+	}
+	return id
+}
+
+// cancelSession cancels the running statement of the session,
+// the session is killed if the server can not cancel it.
+func (db *Database) cancelSession(server int, id int64) {
+	db.mu.Lock()
+	conn, err := db.connect(server)
+	db.mu.Unlock()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	ctx := context.Background()
+	if rs := conn.Exec(ctx, fmt.Sprintf("ALTER SYSTEM CANCEL SESSION %d", id)); rs.Err() != nil {
+		conn.Exec(ctx, fmt.Sprintf("ALTER SYSTEM KILL SESSION %d", id))
+	}
+}
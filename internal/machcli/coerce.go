@@ -0,0 +1,307 @@
+package machcli
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/machbase/neo-server/v8/api"
+)
+
+// timeFormat parses the time values of the input,
+// the numbers are epoch times in the unit, the strings are in the layout.
+type timeFormat struct {
+	unit   time.Duration // unit of the epoch time, 0 if the format is a layout
+	layout string
+	loc    *time.Location
+}
+
+func newTimeFormat(format string, tz string) (timeFormat, error) {
+	tf := timeFormat{loc: time.Local}
+	switch strings.ToLower(tz) {
+	case "", "local":
+	case "utc":
+		tf.loc = time.UTC
+	default:
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return tf, fmt.Errorf("failed to load location '%s': %v", tz, err)
+		}
+		tf.loc = loc
+	}
+	switch strings.ToLower(format) {
+	case "", "ns":
+		tf.unit = time.Nanosecond
+	case "us":
+		tf.unit = time.Microsecond
+	case "ms":
+		tf.unit = time.Millisecond
+	case "s":
+		tf.unit = time.Second
+	case "rfc3339":
+		tf.layout = time.RFC3339Nano
+	default:
+		tf.layout = format
+	}
+	return tf, nil
+}
+
+func (tf timeFormat) epoch(n float64) time.Time {
+	unit := tf.unit
+	if unit == 0 {
+		unit = time.Nanosecond
+	}
+	sec, frac := math.Modf(n * float64(unit) / float64(time.Second))
+	return time.Unix(int64(sec), int64(frac*float64(time.Second)))
+}
+
+// parse returns the time of the value, the epoch numbers are in the unit of the format,
+// the strings are in the layout of the format or RFC3339 if the format is an epoch unit.
+func (tf timeFormat) parse(v any) (time.Time, error) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, nil
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return tf.parse(n)
+		}
+		n, err := val.Float64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q", val.String())
+		}
+		return tf.epoch(n), nil
+	case int64:
+		if tf.unit != 0 {
+			return time.Unix(0, val*int64(tf.unit)), nil
+		}
+		return tf.epoch(float64(val)), nil
+	case float64:
+		return tf.epoch(val), nil
+	case string:
+		s := strings.TrimSpace(val)
+		if tf.unit != 0 {
+			if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return tf.parse(n)
+			}
+			if n, err := strconv.ParseFloat(s, 64); err == nil {
+				return tf.epoch(n), nil
+			}
+		}
+		layout := tf.layout
+		if layout == "" {
+			layout = time.RFC3339Nano
+		}
+		t, err := time.ParseInLocation(layout, s, tf.loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q for the format %q", val, layout)
+		}
+		return t, nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid time value %v (%T)", v, v)
+	}
+}
+
+// ConvertOption is the options of Converter.
+type ConvertOption struct {
+	Timeformat string `json:"timeformat"`
+	Tz         string `json:"tz"`
+	NullValue  string `json:"nullValue"` // the field that equals to it is NULL
+}
+
+// Converter converts the fields of the text input, e.g. csv, to the values
+// of the Go types that the appender requires for the columns.
+type Converter struct {
+	columns   []*api.Column
+	tf        timeFormat
+	nullValue string
+}
+
+// NewConverter returns the converter of the columns in the order of the fields,
+// the columns are the ones of appender.Columns().
+func NewConverter(columns []*api.Column, opt ConvertOption) (*Converter, error) {
+	tf, err := newTimeFormat(opt.Timeformat, opt.Tz)
+	if err != nil {
+		return nil, err
+	}
+	for _, col := range columns {
+		if col == nil {
+			return nil, errors.New("invalid column")
+		}
+	}
+	return &Converter{columns: columns, tf: tf, nullValue: opt.NullValue}, nil
+}
+
+// Convert returns the values of the fields, the error has the name of the column
+// of the field that can not be converted.
+func (c *Converter) Convert(fields []any) ([]any, error) {
+	if len(fields) != len(c.columns) {
+		return nil, fmt.Errorf("expected %d fields, got %d", len(c.columns), len(fields))
+	}
+	ret := make([]any, len(fields))
+	for i, field := range fields {
+		if s, ok := field.(string); ok && s == c.nullValue {
+			continue
+		}
+		v, err := coerce(c.columns[i], field, c.tf)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %s", c.columns[i].Name, err.Error())
+		}
+		ret[i] = v
+	}
+	return ret, nil
+}
+
+// coerce converts the value of the input to the Go type that the appender requires for the column.
+// The value is a string, json.Number, bool, a JSON object or array, or a value of Go types.
+func coerce(col *api.Column, v any, tf timeFormat) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch col.Type {
+	case api.ColumnTypeShort:
+		return coerceInt(v, 16, true)
+	case api.ColumnTypeUShort:
+		return coerceInt(v, 16, false)
+	case api.ColumnTypeInteger:
+		return coerceInt(v, 32, true)
+	case api.ColumnTypeUInteger:
+		return coerceInt(v, 32, false)
+	case api.ColumnTypeLong:
+		return coerceInt(v, 64, true)
+	case api.ColumnTypeULong:
+		return coerceInt(v, 64, false)
+	case api.ColumnTypeFloat:
+		f, err := coerceFloat(v, 32)
+		if err != nil {
+			return nil, err
+		}
+		return float32(f), nil
+	case api.ColumnTypeDouble:
+		return coerceFloat(v, 64)
+	case api.ColumnTypeDatetime:
+		return tf.parse(v)
+	case api.ColumnTypeVarchar, api.ColumnTypeText, api.ColumnTypeClob:
+		switch val := v.(type) {
+		case string:
+			return val, nil
+		case json.Number:
+			return val.String(), nil
+		case []byte:
+			return string(val), nil
+		case map[string]any, []any:
+			b, err := json.Marshal(val)
+			return string(b), err
+		default:
+			return fmt.Sprint(val), nil
+		}
+	case api.ColumnTypeJSON:
+		if s, ok := v.(string); ok {
+			if !json.Valid([]byte(s)) {
+				return nil, fmt.Errorf("invalid json %q", s)
+			}
+			return s, nil
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case api.ColumnTypeBinary, api.ColumnTypeBlob:
+		switch val := v.(type) {
+		case []byte:
+			return val, nil
+		case string:
+			return decodeBinary(val)
+		default:
+			return nil, fmt.Errorf("invalid binary value %v (%T)", v, v)
+		}
+	case api.ColumnTypeIPv4, api.ColumnTypeIPv6:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid ip address %v (%T)", v, v)
+		}
+		ip := net.ParseIP(strings.TrimSpace(s))
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ip address %q", s)
+		}
+		if col.Type == api.ColumnTypeIPv4 && ip.To4() == nil {
+			return nil, fmt.Errorf("%q is not an ipv4 address", s)
+		}
+		return ip, nil
+	default:
+		return nil, fmt.Errorf("unsupported column type %s", col.Type.String())
+	}
+}
+
+// coerceInt parses the integer of the bits, the values out of the range are errors.
+func coerceInt(v any, bits int, signed bool) (any, error) {
+	var s string
+	switch val := v.(type) {
+	case json.Number:
+		s = val.String()
+	case string:
+		s = strings.TrimSpace(val)
+	case int64, uint64:
+		return integerConverter(bits, signed)(val)
+	default:
+		return nil, fmt.Errorf("invalid integer value %v (%T)", v, v)
+	}
+	if signed {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", s)
+		}
+		return integerConverter(bits, signed)(n)
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid unsigned integer %q", s)
+	}
+	return integerConverter(bits, signed)(n)
+}
+
+func coerceFloat(v any, bits int) (float64, error) {
+	var s string
+	switch val := v.(type) {
+	case json.Number:
+		s = val.String()
+	case string:
+		s = strings.TrimSpace(val)
+	case float64:
+		if bits == 32 && math.Abs(val) > math.MaxFloat32 && !math.IsInf(val, 0) {
+			return 0, fmt.Errorf("value %v is out of range", val)
+		}
+		return val, nil
+	case int64:
+		return float64(val), nil
+	default:
+		return 0, fmt.Errorf("invalid float value %v (%T)", v, v)
+	}
+	f, err := strconv.ParseFloat(s, bits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid float %q", s)
+	}
+	return f, nil
+}
+
+// decodeBinary decodes the hex string prefixed with "0x" or "\x", otherwise base64.
+func decodeBinary(s string) ([]byte, error) {
+	if h, ok := strings.CutPrefix(s, "0x"); ok {
+		return hex.DecodeString(h)
+	}
+	if h, ok := strings.CutPrefix(s, `\x`); ok {
+		return hex.DecodeString(h)
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 binary %q", s)
+	}
+	return b, nil
+}
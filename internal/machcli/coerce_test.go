@@ -0,0 +1,77 @@
+package machcli
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/machbase/neo-server/v8/api"
+)
+
+func TestConverter(t *testing.T) {
+	columns := testColumns(
+		"S", api.ColumnTypeShort, "US", api.ColumnTypeUShort,
+		"I", api.ColumnTypeInteger, "UI", api.ColumnTypeUInteger,
+		"L", api.ColumnTypeLong, "UL", api.ColumnTypeULong,
+		"F", api.ColumnTypeFloat, "D", api.ColumnTypeDouble,
+		"T", api.ColumnTypeDatetime, "V", api.ColumnTypeVarchar,
+		"J", api.ColumnTypeJSON, "B", api.ColumnTypeBinary, "BL", api.ColumnTypeBlob,
+		"IP4", api.ColumnTypeIPv4, "IP6", api.ColumnTypeIPv6,
+	)
+	conv, err := NewConverter(columns, ConvertOption{Timeformat: "ms", Tz: "UTC", NullValue: "NULL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := []any{"-32768", "65535", "-2147483648", "4294967295", "-9223372036854775808", "18446744073709551615",
+		"1.5", "2.25", "1700000000000", "text", ` {"a": 1} `, "0x00ff", "AP8=", "10.0.0.1", "::1"}
+
+	got, err := conv.Convert(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []any{int16(-32768), uint16(65535), int32(-2147483648), uint32(4294967295),
+		int64(-9223372036854775808), uint64(18446744073709551615), float32(1.5), 2.25,
+		time.UnixMilli(1700000000000).UTC(), "text", ` {"a": 1} `, []byte{0x00, 0xff}, []byte{0x00, 0xff},
+		net.ParseIP("10.0.0.1"), net.ParseIP("::1")}
+	checkRow(t, "valid", got, expect)
+
+	nulls := make([]any, len(columns))
+	for i := range nulls {
+		nulls[i] = "NULL"
+	}
+	if got, err := conv.Convert(nulls); err != nil || !reflect.DeepEqual(got, make([]any, len(columns))) {
+		t.Errorf("expected all nil, got %v %v", got, err)
+	}
+
+	invalid := []struct {
+		index int
+		field string
+		err   string
+	}{
+		{0, "32768", "column S: value 32768 is out of range"},
+		{1, "-1", `column US: invalid unsigned integer "-1"`},
+		{2, "1.5", `column I: invalid integer "1.5"`},
+		{3, "4294967296", "column UI: value 4294967296 is out of range"},
+		{5, "18446744073709551616", "column UL: invalid unsigned integer"},
+		{6, "1e39", `column F: invalid float "1e39"`},
+		{7, "abc", `column D: invalid float "abc"`},
+		{8, "yesterday", `column T: invalid time "yesterday"`},
+		{10, "{not json", `column J: invalid json "{not json"`},
+		{11, "0xzz", "column B: encoding/hex"},
+		{12, "not base64!", `column BL: invalid base64 binary "not base64!"`},
+		{13, "::1", `column IP4: "::1" is not an ipv4 address`},
+		{14, "10.0.0", `column IP6: invalid ip address "10.0.0"`},
+	}
+	for _, tt := range invalid {
+		fields := append([]any{}, valid...)
+		fields[tt.index] = tt.field
+		if _, err := conv.Convert(fields); err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("%s: expected error %q, got %v", tt.field, tt.err, err)
+		}
+	}
+	if _, err := conv.Convert(valid[1:]); err == nil {
+		t.Error("expected error of the number of fields")
+	}
+}
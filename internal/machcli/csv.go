@@ -0,0 +1,253 @@
+package machcli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/machbase/neo-server/v8/api"
+)
+
+// CSVOption is the options of CSVReader.
+type CSVOption struct {
+	Separator  string `json:"separator"` // "," for csv, "\t" for tsv
+	Header     string `json:"header"`    // skip, columns or none
+	Timeformat string `json:"timeformat"`
+	Tz         string `json:"tz"`
+	NullValue  string `json:"nullValue"`
+	Compress   string `json:"compress"` // none, gzip, zstd, bzip2 or auto
+	DryRun     bool   `json:"dryRun"`   // convert the values without appending
+	// ResumeFromLine skips the lines before the line number, e.g. to resume a failed import
+	ResumeFromLine int64 `json:"resumeFromLine"`
+}
+
+// csvTable places the fields of the csv records to the columns of a table.
+type csvTable struct {
+	sep       rune
+	header    string
+	tf        timeFormat
+	nullValue string
+	table     string
+	columns   api.Columns
+	arrival   bool // the table is a log table that has _ARRIVAL_TIME
+}
+
+func newCSVTable(separator string, header string, timeformat string, tz string, nullValue string) (csvTable, error) {
+	t := csvTable{sep: ',', header: strings.ToLower(header), nullValue: nullValue}
+	if separator != "" {
+		sep := []rune(separator)
+		if len(sep) != 1 {
+			return t, fmt.Errorf("invalid separator %q", separator)
+		}
+		t.sep = sep[0]
+	}
+	switch t.header {
+	case "":
+		t.header = "none"
+	case "none", "skip", "columns":
+	default:
+		return t, fmt.Errorf("invalid header option: %s", header)
+	}
+	tf, err := newTimeFormat(timeformat, tz)
+	if err != nil {
+		return t, err
+	}
+	t.tf = tf
+	return t, nil
+}
+
+func (t *csvTable) bind(appender api.Appender) error {
+	cols, err := appender.Columns()
+	if err != nil {
+		return err
+	}
+	t.table, t.columns = appender.TableName(), cols
+	t.arrival = appender.TableType() == api.TableTypeLog && len(cols) > 0 && cols[0].Name == "_ARRIVAL_TIME"
+	return nil
+}
+
+// fieldColumns returns the index of the column of each field by the names of the header,
+// the fields are the columns in order if there are no names.
+func (t *csvTable) fieldColumns(names []string) ([]int, error) {
+	ret := []int{}
+	if names == nil {
+		for i := range t.columns {
+			if i == 0 && t.arrival {
+				continue
+			}
+			ret = append(ret, i)
+		}
+		return ret, nil
+	}
+	for _, name := range names {
+		found := -1
+		for i, col := range t.columns {
+			if strings.EqualFold(strings.TrimSpace(name), col.Name) {
+				found = i
+				break
+			}
+		}
+		if found == -1 {
+			return nil, fmt.Errorf("column '%s' not found in table '%s'", name, t.table)
+		}
+		ret = append(ret, found)
+	}
+	return ret, nil
+}
+
+// values returns the values of all the columns from the fields of the record.
+func (t *csvTable) values(fields []int, rec []string) ([]any, error) {
+	if len(rec) != len(fields) {
+		return nil, fmt.Errorf("expected %d fields, got %d", len(fields), len(rec))
+	}
+	values := make([]any, len(t.columns))
+	if t.arrival {
+		// the server sets the arrival time
+		values[0] = time.Time{}
+	}
+	for i, idx := range fields {
+		if rec[i] == t.nullValue {
+			continue
+		}
+		v, err := coerce(t.columns[idx], rec[i], t.tf)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %s", t.columns[idx].Name, err.Error())
+		}
+		values[idx] = v
+	}
+	return values, nil
+}
+
+// format returns the text of the record for the rejects.
+func (t *csvTable) format(rec []string) string {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	w.Comma = t.sep
+	w.Write(rec)
+	w.Flush()
+	return strings.TrimRight(buf.String(), "\r\n")
+}
+
+func (t *csvTable) newReader(r io.Reader) *csv.Reader {
+	ret := csv.NewReader(r)
+	ret.Comma = t.sep
+	ret.FieldsPerRecord = -1
+	ret.ReuseRecord = true
+	return ret
+}
+
+// CSVReader appends the records of a csv or tsv file to a table,
+// the fields are converted to the types of the columns.
+type CSVReader struct {
+	csvTable
+	in       *input
+	r        *csv.Reader
+	dryRun   bool
+	resume   int64
+	appender api.Appender
+	fields   []int
+}
+
+// NewCSVReader opens the file of the path, "-" for stdin.
+func NewCSVReader(path string, opt CSVOption) (*CSVReader, error) {
+	t, err := newCSVTable(opt.Separator, opt.Header, opt.Timeformat, opt.Tz, opt.NullValue)
+	if err != nil {
+		return nil, err
+	}
+	in, err := openInput(path, opt.Compress)
+	if err != nil {
+		return nil, err
+	}
+	return &CSVReader{
+		csvTable: t,
+		in:       in,
+		r:        t.newReader(in),
+		dryRun:   opt.DryRun,
+		resume:   opt.ResumeFromLine,
+	}, nil
+}
+
+// Size returns the size of the file, -1 for stdin.
+// It is the compressed size if the file is compressed.
+func (r *CSVReader) Size() int64 {
+	return r.in.size
+}
+
+func (r *CSVReader) Close() error {
+	return r.in.Close()
+}
+
+// Bind reads the header and matches the fields to the columns of the appender.
+func (r *CSVReader) Bind(appender api.Appender) error {
+	if err := r.bind(appender); err != nil {
+		return err
+	}
+	var names []string
+	if r.header != "none" {
+		rec, err := r.r.Read()
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("invalid header, %s", err.Error())
+		}
+		if r.header == "columns" {
+			names = rec
+		}
+	}
+	fields, err := r.fieldColumns(names)
+	if err != nil {
+		return err
+	}
+	r.appender, r.fields = appender, fields
+	return nil
+}
+
+// Append reads up to n records and appends them,
+// the records that can not be imported are returned as Rejects.
+// NextLine of the result is the line after the start of the last record,
+// resuming from it skips the records read so far.
+func (r *CSVReader) Append(n int) (*AppendResult, error) {
+	if r.appender == nil {
+		return nil, errors.New("reader is not bound to an appender")
+	}
+	ret := &AppendResult{Rejects: []Reject{}}
+	defer func() { ret.Offset = r.in.Offset() }()
+	for ret.Lines < n {
+		rec, err := r.r.Read()
+		if err == io.EOF {
+			break
+		}
+		if pe := (*csv.ParseError)(nil); errors.As(err, &pe) {
+			ret.Lines++
+			ret.NextLine = int64(pe.StartLine) + 1
+			if int64(pe.StartLine) < r.resume {
+				ret.Skipped++
+			} else {
+				ret.Rejects = append(ret.Rejects, Reject{Line: int64(pe.StartLine), Error: pe.Err.Error()})
+			}
+			continue
+		} else if err != nil {
+			return ret, err
+		}
+		ret.Lines++
+		// the lines are of the file including the header, a quoted field can span lines
+		line, _ := r.r.FieldPos(0)
+		ret.NextLine = int64(line) + 1
+		if int64(line) < r.resume {
+			ret.Skipped++
+			continue
+		}
+		values, err := r.values(r.fields, rec)
+		if err == nil && !r.dryRun {
+			err = r.appender.Append(values...)
+		}
+		if err != nil {
+			ret.Rejects = append(ret.Rejects, Reject{Line: int64(line), Error: err.Error(), Input: r.format(rec)})
+			continue
+		}
+		ret.Rows++
+	}
+	return ret, nil
+}
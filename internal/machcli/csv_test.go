@@ -0,0 +1,182 @@
+package machcli
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/machbase/jsh/engine"
+	"github.com/machbase/neo-server/v8/api"
+	"github.com/machbase/neo-shell/internal/vfs"
+)
+
+const testCSV = "time,VALUE,name\n" +
+	"1700000000000,1.5,tag1\n" +
+	"1700000001000,bad,tag1\n" +
+	"1700000002000,NULL,\"tag,2\"\n" +
+	"1700000003000,3\n"
+
+// testCSV compressed by bzip2, the package has no bzip2 writer
+const testCSVBzip2 = "QlpoOTFBWSZTWWuRv/4AAC5fgAiQEAV6gCIFAwA2owQAIABISqekMnoDUNNAKaAARgD1HhA16jFbLhSCNXE9DIJSzYFJ1sDnIjPC99yu1OEaBwngMR1EFAjf+F3JFOFCQa5G//g="
+
+func TestCSVReader(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("data.csv", []byte(testCSV))
+	gz := &bytes.Buffer{}
+	gw := gzip.NewWriter(gz)
+	gw.Write([]byte(testCSV))
+	gw.Close()
+	write("data.csv.gz", gz.Bytes())
+	zw, _ := zstd.NewWriter(nil)
+	// no extension, detected by the magic bytes
+	write("data.zstd.dat", zw.EncodeAll([]byte(testCSV), nil))
+	bz, _ := base64.StdEncoding.DecodeString(testCSVBzip2)
+	write("data.csv.bz2", bz)
+	vfs.Mount(engine.FSTabs{{MountPoint: "/work", Source: dir}})
+	defer vfs.Mount(nil)
+
+	tests := []struct {
+		path     string
+		compress string
+	}{
+		{"/work/data.csv", "auto"},
+		{"/work/data.csv.gz", ""},
+		{"/work/data.zstd.dat", "auto"},
+		{"/work/data.csv.bz2", "bzip2"},
+	}
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.path), func(t *testing.T) {
+			r, err := NewCSVReader(tt.path, CSVOption{Header: "columns", Timeformat: "ms", Tz: "UTC", NullValue: "NULL", Compress: tt.compress})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			appender := &testAppender{
+				tableType: api.TableTypeTag,
+				columns:   testColumns("NAME", api.ColumnTypeVarchar, "TIME", api.ColumnTypeDatetime, "VALUE", api.ColumnTypeDouble),
+			}
+			if err := r.Bind(appender); err != nil {
+				t.Fatal(err)
+			}
+			rejects := []Reject{}
+			var offset int64
+			for {
+				ret, err := r.Append(2)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ret.Lines == 0 {
+					offset = ret.Offset
+					break
+				}
+				rejects = append(rejects, ret.Rejects...)
+			}
+			if offset != r.Size() {
+				t.Errorf("expected offset %d, got %d", r.Size(), offset)
+			}
+			if len(appender.rows) != 2 {
+				t.Fatalf("expected 2 rows, got %v", appender.rows)
+			}
+			ts := time.UnixMilli(1700000000000).UTC()
+			checkRow(t, "line 2", appender.rows[0], []any{"tag1", ts, 1.5})
+			checkRow(t, "line 4", appender.rows[1], []any{"tag,2", ts.Add(2 * time.Second), nil})
+			if len(rejects) != 2 ||
+				rejects[0].Line != 3 || !strings.HasPrefix(rejects[0].Error, `column VALUE: invalid float "bad"`) || rejects[0].Input != "1700000001000,bad,tag1" ||
+				rejects[1].Line != 5 || rejects[1].Error != "expected 3 fields, got 2" {
+				t.Errorf("unexpected rejects %+v", rejects)
+			}
+		})
+	}
+
+	r, err := NewCSVReader("/work/data.csv", CSVOption{Header: "skip", Timeformat: "ms", NullValue: "NULL", ResumeFromLine: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	appender := &testAppender{
+		tableType: api.TableTypeTag,
+		columns:   testColumns("TIME", api.ColumnTypeDatetime, "VALUE", api.ColumnTypeDouble, "NAME", api.ColumnTypeVarchar),
+	}
+	if err := r.Bind(appender); err != nil {
+		t.Fatal(err)
+	}
+	ret, err := r.Append(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Lines != 4 || ret.Skipped != 2 || ret.Rows != 1 || len(ret.Rejects) != 1 {
+		t.Errorf("expected 4 lines, 2 skipped, 1 row and 1 reject, got %+v", ret)
+	}
+}
+
+func TestCSVReaderResume(t *testing.T) {
+	dir := t.TempDir()
+	// the header is line 1 and the record of "b" spans lines 3 and 4
+	data := "time,value,name\n" +
+		"1700000000000,1,a\n" +
+		"1700000001000,2,\"b\nb\"\n" +
+		"1700000002000,3,c\n"
+	if err := os.WriteFile(filepath.Join(dir, "data.csv"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	vfs.Mount(engine.FSTabs{{MountPoint: "/work", Source: dir}})
+	defer vfs.Mount(nil)
+
+	read := func(resume int64, n int) ([]string, *AppendResult) {
+		t.Helper()
+		r, err := NewCSVReader("/work/data.csv", CSVOption{Header: "skip", Timeformat: "ms", Tz: "UTC", ResumeFromLine: resume})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		appender := &testAppender{
+			tableType: api.TableTypeTag,
+			columns:   testColumns("TIME", api.ColumnTypeDatetime, "VALUE", api.ColumnTypeDouble, "NAME", api.ColumnTypeVarchar),
+		}
+		if err := r.Bind(appender); err != nil {
+			t.Fatal(err)
+		}
+		ret, err := r.Append(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, row := range appender.rows {
+			names = append(names, row[2].(string))
+		}
+		return names, ret
+	}
+
+	names, ret := read(0, 1)
+	if ret.NextLine != 3 || strings.Join(names, "|") != "a" {
+		t.Fatalf("expected next line 3 after a, got %d %v", ret.NextLine, names)
+	}
+	names, ret = read(ret.NextLine, 10)
+	if ret.Skipped != 1 || strings.Join(names, "|") != "b\nb|c" {
+		t.Errorf("expected b and c after skipping a, got %v %+v", names, ret)
+	}
+	if ret.NextLine != 6 {
+		t.Errorf("expected next line 6, got %d", ret.NextLine)
+	}
+
+	// resuming after the record that spans lines
+	_, ret = read(0, 2)
+	if ret.NextLine != 4 {
+		t.Fatalf("expected next line 4 after b, got %d", ret.NextLine)
+	}
+	names, _ = read(ret.NextLine, 10)
+	if strings.Join(names, "|") != "c" {
+		t.Errorf("expected c, got %v", names)
+	}
+}
@@ -0,0 +1,129 @@
+package machcli
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/machbase/neo-shell/internal/vfs"
+)
+
+// input is a file to import that is decompressed by its compression,
+// it counts the bytes read from the file for the progress.
+type input struct {
+	io.Reader
+	file    *countingReader
+	closers []io.Closer
+	size    int64 // size of the file, -1 for stdin
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// openInput opens the file of the path, "-" for stdin.
+// The compression is one of none, gzip, zstd and bzip2, or auto to detect it
+// by the extension of the file or the magic bytes.
+func openInput(name string, compress string) (*input, error) {
+	in := &input{size: -1}
+	var rc io.ReadCloser = io.NopCloser(os.Stdin)
+	if name != "-" {
+		f, size, err := vfs.OpenFile(name)
+		if err != nil {
+			return nil, err
+		}
+		rc, in.size = f, size
+	}
+	in.file = &countingReader{r: rc}
+	in.closers = append(in.closers, rc)
+	br := bufio.NewReaderSize(in.file, 64*1024)
+	compress = strings.ToLower(compress)
+	if compress == "" || compress == "auto" {
+		head, _ := br.Peek(4)
+		compress = compression(name, head)
+	}
+	r, closer, err := decompress(br, compress)
+	if err != nil {
+		in.Close()
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if closer != nil {
+		in.closers = append(in.closers, closer)
+	}
+	in.Reader = r
+	return in, nil
+}
+
+// Offset returns the number of the bytes read from the file.
+func (in *input) Offset() int64 {
+	return in.file.n
+}
+
+func (in *input) Close() error {
+	var err error
+	for i := len(in.closers) - 1; i >= 0; i-- {
+		if e := in.closers[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// compression returns the compression of the file by the extension of the name or the magic bytes of the head.
+func compression(name string, head []byte) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".gz", ".gzip":
+		return "gzip"
+	case ".zst", ".zstd":
+		return "zstd"
+	case ".bz2":
+		return "bzip2"
+	}
+	switch {
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return "gzip"
+	case bytes.HasPrefix(head, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return "zstd"
+	case bytes.HasPrefix(head, []byte("BZh")):
+		return "bzip2"
+	}
+	return "none"
+}
+
+func decompress(r io.Reader, compress string) (io.Reader, io.Closer, error) {
+	switch compress {
+	case "none":
+		return r, nil, nil
+	case "gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, zr, nil
+	case "zstd":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		rc := zr.IOReadCloser()
+		return rc, rc, nil
+	case "bzip2":
+		return bzip2.NewReader(r), nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported compression %q", compress)
+	}
+}
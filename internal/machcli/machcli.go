@@ -3,17 +3,31 @@ package machcli
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/dop251/goja"
 	"github.com/machbase/neo-server/v8/api"
 	"github.com/machbase/neo-server/v8/api/machcli"
+	"github.com/machbase/neo-shell/internal/session"
 )
 
 func Module(rt *goja.Runtime, module *goja.Object) {
 	// Export native functions
 	exports := module.Get("exports").(*goja.Object)
 	exports.Set("NewDatabase", NewDatabase)
+	exports.Set("NewRecordReader", NewRecordReader)
+	exports.Set("NewNDJSONReader", NewNDJSONReader)
+	exports.Set("NewCSVReader", NewCSVReader)
+	exports.Set("NewConverter", NewConverter)
+	exports.Set("BindNamed", BindNamed)
+	exports.Set("ScanMigrations", ScanMigrations)
 	exports.Set("Unbox", api.Unbox)
 }
 
@@ -24,14 +38,55 @@ type Config struct {
 	Password        string `json:"password"`
 	AlternativeHost string `json:"alternativeHost,omitempty"`
 	AlternativePort int    `json:"alternativePort,omitempty"`
+	// Candidates are the mach servers in the order of preference,
+	// Connect() tries the next one when the current server is not available.
+	Candidates []session.HostPort `json:"candidates,omitempty"`
+	// Timeout is the timeout of each statement, e.g. "30s", empty or zero is no timeout.
+	Timeout string `json:"timeout,omitempty"`
+	session.TLSConfig
+}
+
+// endpoints returns the servers to try in order, the configured host comes first.
+func (c Config) endpoints() []session.HostPort {
+	ret := []session.HostPort{{Host: c.Host, Port: c.Port}}
+	add := func(hp session.HostPort) {
+		if hp.Host == "" || hp.Port == 0 || slices.Contains(ret, hp) {
+			return
+		}
+		ret = append(ret, hp)
+	}
+	add(session.HostPort{Host: c.AlternativeHost, Port: c.AlternativePort})
+	for _, hp := range c.Candidates {
+		add(hp)
+	}
+	return ret
+}
+
+type endpoint struct {
+	cli *machcli.Database
+	fwd *tlsForwarder
+}
+
+func (ep *endpoint) Close() error {
+	if ep.fwd != nil {
+		defer ep.fwd.Close()
+	}
+	return ep.cli.Close()
 }
 
 type Database struct {
 	Ctx      context.Context
 	Cancel   context.CancelFunc
-	cli      *machcli.Database
 	user     string
 	password string
+	timeout  time.Duration
+
+	mu        sync.Mutex
+	tls       session.TLSConfig
+	servers   []session.HostPort
+	endpoints map[int]*endpoint // opened endpoints by the index of servers
+	current   int
+	sessions  map[*machcli.Conn]*connSession
 }
 
 func NewDatabase(data string) (*Database, error) {
@@ -44,44 +99,148 @@ func NewDatabase(data string) (*Database, error) {
 	if err := json.Unmarshal([]byte(data), &obj); err != nil {
 		return nil, err
 	}
+	timeout, err := parseTimeout(obj.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	db := &Database{
+		user:      strings.ToUpper(obj.User),
+		timeout:   timeout,
+		password:  obj.Password,
+		tls:       obj.TLSConfig,
+		servers:   obj.endpoints(),
+		endpoints: map[int]*endpoint{},
+		sessions:  map[*machcli.Conn]*connSession{},
+	}
+	var errs []error
+	for i := range db.servers {
+		if _, err := db.open(i); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		db.current = i
+		db.Ctx, db.Cancel = context.WithCancel(context.Background())
+		return db, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// open returns the endpoint of the server at idx, it opens the endpoint if it is not opened yet.
+func (db *Database) open(idx int) (*endpoint, error) {
+	if ep, ok := db.endpoints[idx]; ok {
+		return ep, nil
+	}
+	hp := db.servers[idx]
+	// no alternative server for the driver, Connect() tries the other servers
+	// so that the current is the server that is connected.
 	conf := &machcli.Config{
-		Host:         obj.Host,
-		Port:         obj.Port,
+		Host:         hp.Host,
+		Port:         hp.Port,
 		MaxOpenConn:  -1,
 		MaxOpenQuery: -1,
 	}
-	if obj.AlternativeHost != "" {
-		conf.AlternativeHost = obj.AlternativeHost
-	}
-	if obj.AlternativePort != 0 {
-		conf.AlternativePort = obj.AlternativePort
+	ep := &endpoint{}
+	if db.tls.MachTls {
+		tlsConf, err := db.tls.ClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		ep.fwd, err = newTLSForwarder(conf.Host, conf.Port, tlsConf)
+		if err != nil {
+			return nil, err
+		}
+		conf.Host, conf.Port = ep.fwd.Addr()
 	}
-	db, err := machcli.NewDatabase(conf)
+	cli, err := machcli.NewDatabase(conf)
 	if err != nil {
+		if ep.fwd != nil {
+			ep.fwd.Close()
+		}
 		return nil, err
 	}
-	ctx, cancel := context.WithCancel(context.Background())
-	return &Database{
-		Ctx:      ctx,
-		Cancel:   cancel,
-		cli:      db,
-		user:     strings.ToUpper(obj.User),
-		password: obj.Password,
-	}, nil
+	ep.cli = cli
+	db.endpoints[idx] = ep
+	return ep, nil
 }
 
 func (db *Database) Close() error {
-	return db.cli.Close()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var errs []error
+	for idx, ep := range db.endpoints {
+		if err := ep.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(db.endpoints, idx)
+	}
+	return errors.Join(errs...)
+}
+
+// CancelOnInterrupt cancels the context of the database when SIGINT is received,
+// the running statements of the database are cancelled on the server.
+// It watches the first SIGINT only, the next one has the default behavior.
+// The returned function stops watching the signal.
+func (db *Database) CancelOnInterrupt() func() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-sig:
+			signal.Stop(sig)
+			db.Cancel()
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(sig)
+			close(done)
+		})
+	}
+}
+
+// Cancelled reports whether the context of the database is cancelled.
+func (db *Database) Cancelled() bool {
+	return db.Ctx.Err() != nil
 }
 
 func (db *Database) User() string {
 	return db.user
 }
 
+// Connect connects to the current server, if it fails
+// it tries the other servers in order and the first one that succeeds becomes the current.
 func (db *Database) Connect() (*machcli.Conn, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var errs []error
+	for i := range db.servers {
+		idx := (db.current + i) % len(db.servers)
+		conn, err := db.connect(idx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d %w", db.servers[idx].Host, db.servers[idx].Port, err))
+			continue
+		}
+		db.current = idx
+		db.sessions[conn] = &connSession{server: idx}
+		return conn, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (db *Database) connect(idx int) (*machcli.Conn, error) {
+	ep, err := db.open(idx)
+	if err != nil {
+		return nil, err
+	}
+	if ep.fwd != nil {
+		defer ep.fwd.expect()()
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	conn, err := db.cli.Connect(ctx, api.WithPassword(db.user, db.password))
+	conn, err := ep.cli.Connect(ctx, api.WithPassword(db.user, db.password))
 	if err != nil {
 		return nil, err
 	}
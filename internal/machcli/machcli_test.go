@@ -2,9 +2,12 @@ package machcli
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"net"
 	"os"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -209,6 +212,67 @@ func TestDatabase(t *testing.T) {
 				"    VOLATILE FULL SCAN (_TAG_META)",
 			},
 		},
+		{
+			name: "mach_timeout",
+			script: `
+				const {Client} = require('/usr/lib/machcli');
+				const conf = require("/lib/process").env.get("conf");
+				const join = "SELECT COUNT(*) FROM TIMEOUT_T A, TIMEOUT_T B, TIMEOUT_T C WHERE A.G = B.G AND B.G = C.G";
+				try {
+					db = new Client(conf);
+					conn = db.connect();
+					conn.exec("CREATE TABLE TIMEOUT_T (G INTEGER, V INTEGER)");
+					appender = conn.append("TIMEOUT_T");
+					for (let i = 0; i < 2000; i++) {
+						appender.append(0, i);
+					}
+					appender.close();
+					db.setTimeout('300ms');
+					for (const run of [() => conn.exec(join), () => conn.queryRow(join)]) {
+						try {
+							run();
+							console.println("Error: not timed out");
+						} catch(err) {
+							console.println(err.message.replace(/, .* elapsed$/, ""));
+						}
+					}
+					db.setTimeout('0');
+					conn.exec("DROP TABLE TIMEOUT_T");
+				} catch(err) {
+					console.println("Error: ", err.message);
+				} finally {
+					conn && conn.close();
+				 	db && db.close();
+				}
+			`,
+			output: []string{
+				"query timeout 300ms",
+				"query timeout 300ms",
+			},
+		},
+		{
+			name: "mach_table_schema",
+			script: `
+				const machcli = require('/usr/lib/machcli');
+				const conf = require("/lib/process").env.get("conf");
+				try {
+					db = new machcli.Client(conf);
+					conn = db.connect();
+					schema = machcli.queryTableSchema(conn, db.normalizeTableName("tag"));
+					console.println(machcli.createTableSQL("TAG2", schema));
+					console.println(machcli.queryTableSchema(conn, db.normalizeTableName("no_such_table")));
+				} catch(err) {
+					console.println("Error: ", err.message);
+				} finally {
+					conn && conn.close();
+				 	db && db.close();
+				}
+			`,
+			output: []string{
+				"CREATE TAG TABLE TAG2 (NAME varchar(100) primary key, TIME datetime basetime, VALUE double)",
+				"null",
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -224,3 +288,129 @@ func TestDatabase(t *testing.T) {
 		RunTest(t, tc)
 	}
 }
+
+func TestCancelOnInterrupt(t *testing.T) {
+	db := &Database{}
+	db.Ctx, db.Cancel = context.WithCancel(context.Background())
+	stop := db.CancelOnInterrupt()
+	defer stop()
+	if db.Cancelled() {
+		t.Fatal("database should not be cancelled yet")
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-db.Ctx.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("SIGINT should cancel the database context")
+	}
+	if !db.Cancelled() {
+		t.Errorf("database should be cancelled")
+	}
+
+}
+
+func TestCancelRunningStatement(t *testing.T) {
+	tests := []struct {
+		name   string
+		reason string
+		start  func(db *Database)
+	}{
+		{name: "cancel", reason: "query cancelled", start: func(db *Database) { time.AfterFunc(500*time.Millisecond, db.Cancel) }},
+		{name: "timeout", reason: "query timeout 500ms", start: func(db *Database) { db.SetTimeout("500ms") }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := NewDatabase(fmt.Sprintf(`{"host":"127.0.0.1","port":%d,"user":"sys","password":"manager"}`, testServer.MachPort()))
+			if err != nil {
+				t.Fatalf("NewDatabase failed: %v", err)
+			}
+			defer db.Close()
+			conn, err := db.Connect()
+			if err != nil {
+				t.Fatalf("Connect failed: %v", err)
+			}
+			defer func() {
+				conn.Close()
+				db.Forget(conn)
+			}()
+
+			ctx := context.Background()
+			if rs := conn.Exec(ctx, "CREATE TABLE CANCEL_T (G INTEGER, V INTEGER)"); rs.Err() != nil {
+				t.Fatal(rs.Err())
+			}
+			defer conn.Exec(ctx, "DROP TABLE CANCEL_T")
+			app, err := conn.Appender(ctx, "CANCEL_T")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range 2000 {
+				app.Append(0, i)
+			}
+			app.Close()
+
+			tc.start(db)
+			qc := db.NewQueryContext()
+			db.Watch(conn, qc)
+			tick := time.Now()
+			// the join of 8 billion rows is running when it is cancelled
+			row := conn.QueryRow(qc.Ctx, "SELECT COUNT(*) FROM CANCEL_T A, CANCEL_T B, CANCEL_T C WHERE A.G = B.G AND B.G = C.G")
+			elapsed := time.Since(tick)
+			if reason := qc.Reason(); !strings.HasPrefix(reason, tc.reason) {
+				t.Errorf("expected reason %q, got %q", tc.reason, reason)
+			}
+			qc.Close()
+			if row.Err() == nil {
+				t.Fatal("the running statement should be cancelled")
+			}
+			if elapsed > 10*time.Second {
+				t.Errorf("the statement is cancelled after %s", elapsed)
+			}
+
+			// the session is not killed
+			var count int64
+			if err := conn.QueryRow(ctx, "SELECT COUNT(*) FROM CANCEL_T").Scan(&count); err != nil || count != 2000 {
+				t.Errorf("expected 2000 rows, got %d, %v", count, err)
+			}
+		})
+	}
+}
+
+func TestDatabaseFailover(t *testing.T) {
+	// a port that nobody listens on
+	lsnr, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	downPort := lsnr.Addr().(*net.TCPAddr).Port
+	lsnr.Close()
+
+	conf := fmt.Sprintf(`{"host":"127.0.0.1","port":%d,"user":"sys","password":"manager",`+
+		`"candidates":[{"host":"127.0.0.1","port":%d},{"host":"127.0.0.1","port":%d}]}`,
+		downPort, downPort, testServer.MachPort())
+	db, err := NewDatabase(conf)
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	defer db.Close()
+
+	conn, err := db.Connect()
+	if err != nil {
+		t.Fatalf("Connect should fail over to the next server: %v", err)
+	}
+	conn.Close()
+	if db.servers[db.current].Port != testServer.MachPort() {
+		t.Errorf("expected the current server to be %d, got %+v", testServer.MachPort(), db.servers[db.current])
+	}
+
+	// all servers are down
+	db2, err := NewDatabase(fmt.Sprintf(`{"host":"127.0.0.1","port":%d}`, downPort))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	defer db2.Close()
+	if _, err := db2.Connect(); err == nil {
+		t.Errorf("Connect should fail when no server is available")
+	}
+}
@@ -0,0 +1,80 @@
+package machcli
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/machbase/neo-shell/internal/vfs"
+)
+
+// Migration is a versioned SQL file of the name V<version>__<name>.sql.
+type Migration struct {
+	Version  int64  `json:"version"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Checksum string `json:"checksum"`
+	Text     string `json:"text"`
+}
+
+var migrationFileName = regexp.MustCompile(`^V([0-9]+)__(.+)\.sql$`)
+
+// ScanMigrations returns the migrations of the directory of the virtual path in the order of the versions,
+// the files that do not match V<version>__<name>.sql are ignored.
+// The checksum is the SHA-256 of the file of which line endings are normalized to LF.
+func ScanMigrations(dir string) ([]*Migration, error) {
+	matches, err := vfs.Glob(path.Join(dir, "V*__*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	ret := []*Migration{}
+	for _, p := range matches {
+		m := migrationFileName.FindStringSubmatch(path.Base(p))
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid version, %w", path.Base(p), err)
+		}
+		f, _, err := vfs.OpenFile(p)
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		b = bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+		sum := sha256.Sum256(b)
+		ret = append(ret, &Migration{
+			Version:  version,
+			Name:     m[2],
+			Path:     p,
+			Checksum: hex.EncodeToString(sum[:]),
+			Text:     string(b),
+		})
+	}
+	slices.SortStableFunc(ret, func(a, b *Migration) int {
+		switch {
+		case a.Version < b.Version:
+			return -1
+		case a.Version > b.Version:
+			return 1
+		}
+		return 0
+	})
+	for i := 1; i < len(ret); i++ {
+		if ret[i].Version == ret[i-1].Version {
+			return nil, fmt.Errorf("duplicate version %d of %s and %s", ret[i].Version, path.Base(ret[i-1].Path), path.Base(ret[i].Path))
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,76 @@
+package machcli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/machbase/jsh/engine"
+	"github.com/machbase/neo-shell/internal/vfs"
+)
+
+func TestScanMigrations(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"V2__add_index.sql":    "CREATE INDEX IDX ON EXAMPLE(NAME);\n",
+		"V10__drop_table.sql":  "DROP TABLE OLD;\r\n",
+		"V1__create_table.sql": "CREATE TABLE EXAMPLE (NAME varchar(40));\n",
+		"README.md":            "not a migration",
+		"V3_missing_sep.sql":   "ignored",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vfs.Mount(engine.FSTabs{{MountPoint: "/work", Source: dir}})
+	defer vfs.Mount(nil)
+
+	migrations, err := ScanMigrations("/work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []struct {
+		version int64
+		name    string
+		path    string
+	}{
+		{1, "create_table", "/work/V1__create_table.sql"},
+		{2, "add_index", "/work/V2__add_index.sql"},
+		{10, "drop_table", "/work/V10__drop_table.sql"},
+	}
+	if len(migrations) != len(expect) {
+		t.Fatalf("expected %d migrations, got %d", len(expect), len(migrations))
+	}
+	for i, e := range expect {
+		m := migrations[i]
+		if m.Version != e.version || m.Name != e.name || m.Path != e.path {
+			t.Errorf("migration %d expected %d %s %s, got %d %s %s", i, e.version, e.name, e.path, m.Version, m.Name, m.Path)
+		}
+		if len(m.Checksum) != 64 {
+			t.Errorf("migration %d invalid checksum %q", i, m.Checksum)
+		}
+	}
+	if migrations[2].Text != "DROP TABLE OLD;\n" {
+		t.Errorf("line endings should be normalized, got %q", migrations[2].Text)
+	}
+
+	// the checksum does not depend on the line endings
+	if err := os.WriteFile(filepath.Join(dir, "V10__drop_table.sql"), []byte("DROP TABLE OLD;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	again, err := ScanMigrations("/work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again[2].Checksum != migrations[2].Checksum {
+		t.Errorf("checksum changed by the line endings")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "V01__again.sql"), []byte("SELECT 1;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ScanMigrations("/work"); err == nil || err.Error() != "duplicate version 1 of V01__again.sql and V1__create_table.sql" {
+		t.Errorf("expected duplicate version error, got %v", err)
+	}
+}
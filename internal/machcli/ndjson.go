@@ -0,0 +1,267 @@
+package machcli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/machbase/neo-server/v8/api"
+)
+
+// NDJSONOption is the options of NDJSONReader.
+type NDJSONOption struct {
+	Timeformat string `json:"timeformat"`
+	Tz         string `json:"tz"`
+	Compress   string `json:"compress"` // none, gzip, zstd, bzip2 or auto
+	DryRun     bool   `json:"dryRun"`   // convert the values without appending
+	// ResumeFromLine skips the lines before the line number, e.g. to resume a failed import
+	ResumeFromLine int64 `json:"resumeFromLine"`
+}
+
+// Reject is a line of the input that can not be imported.
+type Reject struct {
+	Path  string `json:"path,omitempty"` // the file of the line if there are multiple inputs
+	Line  int64  `json:"line"`
+	Error string `json:"error"`
+	Input string `json:"input"`
+}
+
+// AppendResult is the result of a batch of lines.
+type AppendResult struct {
+	Lines   int      `json:"lines"`   // number of lines read, 0 at the end of the input
+	Rows    int      `json:"rows"`    // number of rows appended
+	Skipped int      `json:"skipped"` // number of lines skipped before the resuming line
+	Offset  int64    `json:"offset"`  // bytes read from the file so far, compressed bytes if it is compressed
+	Rejects []Reject `json:"rejects"`
+	// NextLine is the line number to resume from after the lines read, 0 if no lines are read
+	NextLine int64 `json:"nextLine"`
+}
+
+// NDJSONReader appends the objects of NDJSON lines to a table,
+// the keys are matched to the columns by name regardless of the case.
+type NDJSONReader struct {
+	r        *bufio.Reader
+	in       *input
+	tf       timeFormat
+	dryRun   bool
+	appender api.Appender
+	columns  api.Columns
+	index    map[string]int // index of the columns by the upper case name
+	arrival  bool           // the table is a log table that has _ARRIVAL_TIME
+	line     int64
+	resume   int64
+}
+
+// NewNDJSONReader opens the file of the path, "-" for stdin.
+func NewNDJSONReader(path string, opt NDJSONOption) (*NDJSONReader, error) {
+	tf, err := newTimeFormat(opt.Timeformat, opt.Tz)
+	if err != nil {
+		return nil, err
+	}
+	in, err := openInput(path, opt.Compress)
+	if err != nil {
+		return nil, err
+	}
+	return &NDJSONReader{
+		r:      bufio.NewReaderSize(in, 64*1024),
+		in:     in,
+		tf:     tf,
+		dryRun: opt.DryRun,
+		resume: opt.ResumeFromLine,
+	}, nil
+}
+
+// Size returns the size of the file, -1 for stdin.
+// It is the compressed size if the file is compressed.
+func (r *NDJSONReader) Size() int64 {
+	return r.in.size
+}
+
+func (r *NDJSONReader) Close() error {
+	return r.in.Close()
+}
+
+func (r *NDJSONReader) Bind(appender api.Appender) error {
+	cols, err := appender.Columns()
+	if err != nil {
+		return err
+	}
+	r.index = map[string]int{}
+	for i, c := range cols {
+		r.index[strings.ToUpper(c.Name)] = i
+	}
+	r.arrival = appender.TableType() == api.TableTypeLog && len(cols) > 0 && cols[0].Name == "_ARRIVAL_TIME"
+	r.appender, r.columns = appender, cols
+	return nil
+}
+
+// Append reads up to n lines and appends the rows of them,
+// the lines that can not be imported are returned as Rejects.
+func (r *NDJSONReader) Append(n int) (*AppendResult, error) {
+	if r.appender == nil {
+		return nil, errors.New("reader is not bound to an appender")
+	}
+	ret := &AppendResult{Rejects: []Reject{}}
+	defer func() { ret.Offset = r.in.Offset() }()
+	for ret.Lines < n {
+		b, err := r.r.ReadBytes('\n')
+		if len(b) == 0 && err != nil {
+			if err == io.EOF {
+				break
+			}
+			return ret, err
+		}
+		r.line++
+		ret.Lines++
+		ret.NextLine = r.line + 1
+		if r.line < r.resume {
+			ret.Skipped++
+			continue
+		}
+		text := strings.TrimRight(string(b), "\r\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if cnt, err := r.appendLine(text); err != nil {
+			ret.Rejects = append(ret.Rejects, Reject{Line: r.line, Error: err.Error(), Input: text})
+		} else {
+			ret.Rows += cnt
+		}
+	}
+	return ret, nil
+}
+
+// appendLine converts all the records of the line before appending any of them.
+func (r *NDJSONReader) appendLine(text string) (int, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return 0, fmt.Errorf("invalid json, %s", err.Error())
+	}
+	if dec.More() {
+		return 0, errors.New("invalid json, unexpected data after the object")
+	}
+	records, err := r.records(v)
+	if err != nil {
+		return 0, err
+	}
+	rows := make([][]any, 0, len(records))
+	for _, rec := range records {
+		if len(rec) == 0 {
+			return 0, fmt.Errorf("no column of %s in the object", r.appender.TableName())
+		}
+		values := make([]any, len(r.columns))
+		if r.arrival {
+			// the server sets the arrival time
+			values[0] = time.Time{}
+		}
+		for idx, x := range rec {
+			cv, err := coerce(r.columns[idx], x, r.tf)
+			if err != nil {
+				return 0, fmt.Errorf("column %s: %s", r.columns[idx].Name, err.Error())
+			}
+			values[idx] = cv
+		}
+		rows = append(rows, values)
+	}
+	if r.dryRun {
+		return len(rows), nil
+	}
+	for i, values := range rows {
+		if err := r.appender.Append(values...); err != nil {
+			return i, err
+		}
+	}
+	return len(rows), nil
+}
+
+// records returns the values of the columns by index for each record of the value.
+// A line is an object or an array of objects, the fields of a nested object fill
+// the columns that are not set by the outer object, and the objects of a nested array
+// are the records that share the fields of the outer object, e.g.
+//
+//	{"name":"tag1", "time":1700000000, "value":1.5}
+//	{"device":"d1", "data":{"name":"tag1", "time":1700000000, "value":1.5}}
+//	{"name":"tag1", "records":[{"time":1700000000, "value":1.5}, {"time":1700000001, "value":1.6}]}
+func (r *NDJSONReader) records(v any) ([]map[int]any, error) {
+	switch val := v.(type) {
+	case []any:
+		ret := []map[int]any{}
+		for _, elm := range val {
+			if _, ok := elm.(map[string]any); !ok {
+				return nil, errors.New("not a json object")
+			}
+			recs, err := r.records(elm)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, recs...)
+		}
+		return ret, nil
+	case map[string]any:
+		base := map[int]any{}
+		objs := []map[string]any{}
+		var nested []any
+		var nestedKey string
+		for _, key := range slices.Sorted(maps.Keys(val)) {
+			x := val[key]
+			if idx, ok := r.index[strings.ToUpper(key)]; ok {
+				base[idx] = x
+				continue
+			}
+			switch y := x.(type) {
+			case map[string]any:
+				objs = append(objs, y)
+			case []any:
+				if !isObjects(y) {
+					continue
+				}
+				if nested != nil {
+					return nil, fmt.Errorf("multiple nested records %q and %q", nestedKey, key)
+				}
+				nested, nestedKey = y, key
+			}
+		}
+		for _, obj := range objs {
+			for _, key := range slices.Sorted(maps.Keys(obj)) {
+				if idx, ok := r.index[strings.ToUpper(key)]; ok {
+					if _, set := base[idx]; !set {
+						base[idx] = obj[key]
+					}
+				}
+			}
+		}
+		if nested == nil {
+			return []map[int]any{base}, nil
+		}
+		ret := make([]map[int]any, 0, len(nested))
+		for _, elm := range nested {
+			rec := maps.Clone(base)
+			for key, x := range elm.(map[string]any) {
+				if idx, ok := r.index[strings.ToUpper(key)]; ok {
+					rec[idx] = x
+				}
+			}
+			ret = append(ret, rec)
+		}
+		return ret, nil
+	default:
+		return nil, errors.New("not a json object")
+	}
+}
+
+func isObjects(arr []any) bool {
+	for _, elm := range arr {
+		if _, ok := elm.(map[string]any); !ok {
+			return false
+		}
+	}
+	return len(arr) > 0
+}
@@ -0,0 +1,122 @@
+package machcli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/machbase/jsh/engine"
+	"github.com/machbase/neo-server/v8/api"
+	"github.com/machbase/neo-shell/internal/vfs"
+)
+
+func TestNDJSONReader(t *testing.T) {
+	lines := []string{
+		`{"name":"tag1","time":1700000000000,"value":1.5}`,
+		`{"NAME":"tag1","Time":"2023-11-14T22:13:21Z","value":"2.5","extra":true}`,
+		``,
+		`{"device":"d1","data":{"name":"tag2","time":1700000002000,"value":3}}`,
+		`{"name":"tag3","records":[{"time":1700000003000,"value":4},{"time":1700000004000,"value":null}]}`,
+		`{"name":"tag4","time":"yesterday","value":1}`,
+		`{"name":"tag5","time":1700000005000,"value":1} trailing`,
+		`[{"name":"tag6","time":1700000006000,"value":6,"ip":"10.0.0.1"},{"name":"tag6","time":1700000007000,"value":7,"ip":"::1"}]`,
+		`{"device":"d2"}`,
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "data.ndjson"), []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	vfs.Mount(engine.FSTabs{{MountPoint: "/work", Source: dir}})
+	defer vfs.Mount(nil)
+
+	r, err := NewNDJSONReader("/work/data.ndjson", NDJSONOption{Timeformat: "ms", Tz: "UTC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	appender := &testAppender{
+		tableType: api.TableTypeTag,
+		columns: testColumns("NAME", api.ColumnTypeVarchar, "TIME", api.ColumnTypeDatetime,
+			"VALUE", api.ColumnTypeDouble, "IP", api.ColumnTypeIPv4),
+	}
+	if err := r.Bind(appender); err != nil {
+		t.Fatal(err)
+	}
+	lineCount, rowCount := 0, 0
+	rejects := []Reject{}
+	for {
+		ret, err := r.Append(4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ret.Lines == 0 {
+			break
+		}
+		lineCount += ret.Lines
+		rowCount += ret.Rows
+		rejects = append(rejects, ret.Rejects...)
+	}
+	if lineCount != len(lines) || rowCount != 5 || len(appender.rows) != 5 {
+		t.Fatalf("expected %d lines and 5 rows, got %d lines, %d rows, %d appended", len(lines), lineCount, rowCount, len(appender.rows))
+	}
+	ts := time.UnixMilli(1700000000000).UTC()
+	checkRow(t, "line 1", appender.rows[0], []any{"tag1", ts, 1.5, nil})
+	checkRow(t, "line 2", appender.rows[1], []any{"tag1", ts.Add(time.Second), 2.5, nil})
+	checkRow(t, "line 4", appender.rows[2], []any{"tag2", ts.Add(2 * time.Second), 3.0, nil})
+	checkRow(t, "line 5", appender.rows[4], []any{"tag3", ts.Add(4 * time.Second), nil, nil})
+
+	expectRejects := []struct {
+		line int64
+		err  string
+	}{
+		{6, `column TIME: invalid time "yesterday"`},
+		{7, "invalid json, unexpected data after the object"},
+		{8, `column IP: "::1" is not an ipv4 address`},
+		{9, "no column of EXAMPLE in the object"},
+	}
+	if len(rejects) != len(expectRejects) {
+		t.Fatalf("expected %d rejects, got %+v", len(expectRejects), rejects)
+	}
+	for i, want := range expectRejects {
+		if rejects[i].Line != want.line || !strings.HasPrefix(rejects[i].Error, want.err) || rejects[i].Input != lines[want.line-1] {
+			t.Errorf("reject %d: expected line %d %q, got %+v", i, want.line, want.err, rejects[i])
+		}
+	}
+}
+
+func TestNDJSONReaderResume(t *testing.T) {
+	lines := []string{
+		`{"name":"tag1","time":1700000000000,"value":1}`,
+		`{"name":"tag1","time":"broken","value":2}`,
+		`{"name":"tag1","time":1700000002000,"value":3}`,
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "data.ndjson"), []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	vfs.Mount(engine.FSTabs{{MountPoint: "/work", Source: dir}})
+	defer vfs.Mount(nil)
+
+	r, err := NewNDJSONReader("/work/data.ndjson", NDJSONOption{Timeformat: "ms", Tz: "UTC", ResumeFromLine: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	appender := &testAppender{
+		tableType: api.TableTypeTag,
+		columns:   testColumns("NAME", api.ColumnTypeVarchar, "TIME", api.ColumnTypeDatetime, "VALUE", api.ColumnTypeDouble),
+	}
+	if err := r.Bind(appender); err != nil {
+		t.Fatal(err)
+	}
+	ret, err := r.Append(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Lines != 3 || ret.Skipped != 2 || ret.Rows != 1 || len(ret.Rejects) != 0 {
+		t.Fatalf("expected 3 lines, 2 skipped, 1 row, got %+v", ret)
+	}
+	checkRow(t, "line 3", appender.rows[0], []any{"tag1", time.UnixMilli(1700000002000).UTC(), 3.0})
+}
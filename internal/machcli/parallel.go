@@ -0,0 +1,544 @@
+package machcli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/machbase/neo-server/v8/api"
+	"github.com/machbase/neo-shell/internal/vfs"
+)
+
+// ParallelOption is the options of Database.ImportParallel.
+type ParallelOption struct {
+	Workers    int    `json:"workers"`
+	Separator  string `json:"separator"` // "," for csv, "\t" for tsv
+	Header     string `json:"header"`    // skip, columns or none
+	Timeformat string `json:"timeformat"`
+	Tz         string `json:"tz"`
+	NullValue  string `json:"nullValue"`
+	Compress   string `json:"compress"` // none, gzip, zstd, bzip2 or auto
+	DryRun     bool   `json:"dryRun"`
+}
+
+// ImportStatus is the progress of a parallel import.
+type ImportStatus struct {
+	Done    bool     `json:"done"`
+	Error   string   `json:"error"`  // the error that stopped the import
+	Total   int64    `json:"total"`  // bytes of the inputs, compressed bytes if they are compressed
+	Offset  int64    `json:"offset"` // bytes read from the inputs so far
+	Lines   int64    `json:"lines"`
+	Rows    int64    `json:"rows"`
+	Rejects []Reject `json:"rejects"` // the lines rejected since the last Wait()
+}
+
+// WorkerResult is the result of the appender of a worker.
+type WorkerResult struct {
+	Worker  int    `json:"worker"`
+	Success int64  `json:"success"`
+	Fail    int64  `json:"fail"`
+	Error   string `json:"error"`
+}
+
+// opener opens an appender and returns the function that releases its connection.
+type opener func() (api.Appender, func() error, error)
+
+// chunk is a byte range of an input file that starts and ends at line boundaries,
+// a compressed file is a chunk as a whole.
+type chunk struct {
+	path       string
+	file       vfs.File
+	compress   string
+	start      int64
+	end        int64
+	prev       *chunk // the chunk before it in the same file, nil for the first chunk
+	skipHeader bool   // the chunk starts with the header
+	fields     []int  // index of the column of each field
+
+	// the lines of the chunks are counted while they are imported,
+	// the rejects are numbered when the lines before the chunk are known.
+	line     int64 // number of the lines before the chunk, valid if numbered
+	numbered bool
+	lines    int64 // number of the lines of the chunk, valid if counted
+	counted  bool
+	rejects  []Reject // rejects of the lines in the chunk until it is numbered
+}
+
+// open returns the reader of the chunk that is decompressed if the file is compressed,
+// the bytes read from the file are added to offset if it is not nil.
+func (c *chunk) open(offset *atomic.Int64) (io.Reader, io.Closer, error) {
+	r := io.Reader(io.NewSectionReader(c.file, c.start, c.end-c.start))
+	if offset != nil {
+		r = &offsetReader{r: r, n: offset}
+	}
+	if c.compress == "none" {
+		return r, nil, nil
+	}
+	return decompress(bufio.NewReaderSize(r, 64*1024), c.compress)
+}
+
+// offsetReader adds the bytes read to n that is shared by the workers.
+type offsetReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (o *offsetReader) Read(b []byte) (int, error) {
+	n, err := o.r.Read(b)
+	o.n.Add(int64(n))
+	return n, err
+}
+
+// lineCounter counts the lines of the bytes read, the last line may not end with a newline.
+type lineCounter struct {
+	r     io.Reader
+	lines int64
+	last  byte
+}
+
+func (lc *lineCounter) Read(b []byte) (int, error) {
+	n, err := lc.r.Read(b)
+	if n > 0 {
+		lc.lines += int64(bytes.Count(b[:n], []byte{'\n'}))
+		lc.last = b[n-1]
+	}
+	return n, err
+}
+
+func (lc *lineCounter) count() int64 {
+	if lc.last != 0 && lc.last != '\n' {
+		return lc.lines + 1
+	}
+	return lc.lines
+}
+
+// ParallelImport imports csv files by the workers that have their own connections and appenders.
+// The files are split into byte ranges at line boundaries, so the records must not span lines.
+type ParallelImport struct {
+	csvTable
+	opt   ParallelOption
+	files []vfs.File
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	total  int64
+	offset atomic.Int64
+	lines  atomic.Int64
+	rows   atomic.Int64
+
+	mu      sync.Mutex
+	chunks  []*chunk
+	rejects []Reject // the rejects that are numbered
+	results []WorkerResult
+	err     error
+}
+
+// ImportParallel starts to import the files of the paths into the table,
+// the paths are absolute and can be glob patterns.
+func (db *Database) ImportParallel(table string, paths []string, opt ParallelOption) (*ParallelImport, error) {
+	return startParallelImport(paths, opt, func() (api.Appender, func() error, error) {
+		conn, err := db.Connect()
+		if err != nil {
+			return nil, nil, err
+		}
+		app, err := conn.Appender(db.Ctx, table)
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+		return app, conn.Close, nil
+	})
+}
+
+func startParallelImport(paths []string, opt ParallelOption, open opener) (*ParallelImport, error) {
+	if opt.Workers < 1 {
+		opt.Workers = 1
+	}
+	t, err := newCSVTable(opt.Separator, opt.Header, opt.Timeformat, opt.Tz, opt.NullValue)
+	if err != nil {
+		return nil, err
+	}
+	p := &ParallelImport{csvTable: t, opt: opt, rejects: []Reject{}, done: make(chan struct{})}
+	files, err := expand(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	// the first appender reports the errors of the table before starting
+	app, release, err := open()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.bind(app); err == nil {
+		var chunks []*chunk
+		if chunks, err = p.split(files); err == nil {
+			p.ctx, p.cancel = context.WithCancel(context.Background())
+			go p.run(chunks, app, release, open)
+			return p, nil
+		}
+	}
+	app.Close()
+	release()
+	p.closeFiles()
+	return nil, err
+}
+
+// expand returns the files of the paths and the glob patterns.
+func expand(paths []string) ([]string, error) {
+	ret := []string{}
+	for _, path := range paths {
+		if !strings.ContainsAny(path, "*?[") {
+			ret = append(ret, path)
+			continue
+		}
+		matches, err := vfs.Glob(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s: no such files", path)
+		}
+		ret = append(ret, matches...)
+	}
+	if len(ret) == 0 {
+		return nil, errors.New("no input files")
+	}
+	return ret, nil
+}
+
+// split reads the headers of the files and splits them into the chunks,
+// a file is split into the ranges for the workers if there are fewer files than the workers.
+func (p *ParallelImport) split(paths []string) ([]*chunk, error) {
+	parts := 1
+	if len(paths) < p.opt.Workers {
+		parts = (p.opt.Workers + len(paths) - 1) / len(paths)
+	}
+	chunks := []*chunk{}
+	for _, path := range paths {
+		f, size, err := vfs.OpenFile(path)
+		if err != nil {
+			return nil, err
+		}
+		p.files = append(p.files, f)
+		compress := strings.ToLower(p.opt.Compress)
+		if compress == "" || compress == "auto" {
+			head := make([]byte, 4)
+			n, _ := f.ReadAt(head, 0)
+			compress = compression(path, head[:n])
+		}
+		p.total += size
+		whole := &chunk{path: path, file: f, compress: compress, end: size, numbered: true}
+		start, fields, err := p.header(whole)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if compress != "none" {
+			// a compressed file can not be split
+			whole.fields, whole.skipHeader = fields, start > 0
+			chunks = append(chunks, whole)
+			continue
+		}
+		prev := start
+		var last *chunk
+		for i := 1; i <= parts; i++ {
+			end := size
+			if i < parts {
+				if end, err = lineBoundary(f, start+(size-start)*int64(i)/int64(parts), start, size); err != nil {
+					return nil, err
+				}
+			}
+			if end <= prev {
+				continue
+			}
+			c := &chunk{path: path, file: f, compress: compress, start: prev, end: end, fields: fields, prev: last}
+			if last == nil {
+				// the header is read already
+				c.numbered = true
+				if start > 0 {
+					c.line = 1
+					p.lines.Add(1)
+					p.offset.Add(start)
+				}
+			}
+			chunks = append(chunks, c)
+			prev, last = end, c
+		}
+	}
+	return chunks, nil
+}
+
+// header returns the length of the header line and the columns of the fields.
+func (p *ParallelImport) header(c *chunk) (int64, []int, error) {
+	if p.csvTable.header == "none" {
+		fields, err := p.fieldColumns(nil)
+		return 0, fields, err
+	}
+	r, closer, err := c.open(nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, nil, err
+	}
+	var names []string
+	if p.csvTable.header == "columns" {
+		if names, err = p.newReader(strings.NewReader(line)).Read(); err != nil {
+			return 0, nil, fmt.Errorf("invalid header, %s", err.Error())
+		}
+	}
+	fields, err := p.fieldColumns(names)
+	return int64(len(line)), fields, err
+}
+
+// lineBoundary returns the offset of the line that follows the offset, a line that starts at the offset is kept.
+func lineBoundary(f io.ReaderAt, off int64, start int64, size int64) (int64, error) {
+	if off <= start {
+		return start, nil
+	}
+	buf := make([]byte, 64*1024)
+	for pos := off - 1; pos < size; {
+		n, err := f.ReadAt(buf, pos)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return pos + int64(i) + 1, nil
+		}
+		pos += int64(n)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	return size, nil
+}
+
+func (p *ParallelImport) run(chunks []*chunk, app api.Appender, release func() error, open opener) {
+	defer close(p.done)
+	defer p.closeFiles()
+	workers := min(p.opt.Workers, max(len(chunks), 1))
+	p.results = make([]WorkerResult, workers)
+	p.chunks = chunks
+	queue := make(chan *chunk, len(chunks))
+	for _, c := range chunks {
+		queue <- c
+	}
+	close(queue)
+
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int, app api.Appender, release func() error) {
+			defer wg.Done()
+			p.results[w] = p.work(w, app, release, queue, open)
+		}(w, app, release)
+		app, release = nil, nil
+	}
+	wg.Wait()
+	p.countRest()
+}
+
+// number moves the rejects of the chunks that the lines before them are known to p.rejects
+// with the line numbers of the file, p.mu is held by the caller.
+func (p *ParallelImport) number() {
+	for _, c := range p.chunks {
+		if !c.numbered && c.prev.numbered && c.prev.counted {
+			c.line, c.numbered = c.prev.line+c.prev.lines, true
+		}
+		if !c.numbered {
+			continue
+		}
+		for _, r := range c.rejects {
+			r.Line += c.line
+			p.rejects = append(p.rejects, r)
+		}
+		c.rejects = nil
+	}
+}
+
+// countRest counts the lines of the chunks that are not imported when the import stopped,
+// so that the rejects of the chunks after them are numbered.
+func (p *ParallelImport) countRest() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, c := range p.chunks {
+		if c.counted || !slices.ContainsFunc(p.chunks[i+1:], func(n *chunk) bool { return len(n.rejects) > 0 }) {
+			continue
+		}
+		n, err := chunkLines(c)
+		if err != nil {
+			break
+		}
+		c.lines, c.counted = n, true
+	}
+	p.number()
+}
+
+// chunkLines returns the number of the lines of the chunk.
+func chunkLines(c *chunk) (int64, error) {
+	r, closer, err := c.open(nil)
+	if err != nil {
+		return 0, err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+	lc := &lineCounter{r: r}
+	if _, err := io.Copy(io.Discard, lc); err != nil {
+		return 0, err
+	}
+	return lc.count(), nil
+}
+
+func (p *ParallelImport) work(w int, app api.Appender, release func() error, queue <-chan *chunk, open opener) WorkerResult {
+	ret := WorkerResult{Worker: w}
+	if app == nil {
+		var err error
+		if app, release, err = open(); err != nil {
+			ret.Error = err.Error()
+			p.fail(err)
+			return ret
+		}
+	}
+	defer release()
+	for c := range queue {
+		if err := p.importChunk(c, app); err != nil {
+			p.fail(err)
+			break
+		}
+	}
+	succ, fail, err := app.Close()
+	ret.Success, ret.Fail = succ, fail
+	if err != nil {
+		ret.Error = err.Error()
+	}
+	return ret
+}
+
+func (p *ParallelImport) importChunk(c *chunk, app api.Appender) error {
+	cr, closer, err := c.open(&p.offset)
+	if err != nil {
+		return err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+	lc := &lineCounter{r: cr}
+	r := p.newReader(lc)
+	if c.skipHeader {
+		if _, err := r.Read(); err != nil && err != io.EOF {
+			return err
+		}
+		p.lines.Add(1)
+	}
+	for p.ctx.Err() == nil {
+		rec, err := r.Read()
+		if err == io.EOF {
+			p.mu.Lock()
+			c.lines, c.counted = lc.count(), true
+			p.number()
+			p.mu.Unlock()
+			return nil
+		}
+		if pe := (*csv.ParseError)(nil); errors.As(err, &pe) {
+			p.lines.Add(1)
+			p.reject(c, Reject{Path: c.path, Line: int64(pe.StartLine), Error: pe.Err.Error()})
+			continue
+		} else if err != nil {
+			return err
+		}
+		line, _ := r.FieldPos(0)
+		p.lines.Add(1)
+		values, err := p.values(c.fields, rec)
+		if err == nil && !p.opt.DryRun {
+			err = app.Append(values...)
+		}
+		if err != nil {
+			p.reject(c, Reject{Path: c.path, Line: int64(line), Error: err.Error(), Input: p.format(rec)})
+			continue
+		}
+		p.rows.Add(1)
+	}
+	return nil
+}
+
+// reject keeps the reject of the line in the chunk until the chunk is numbered.
+func (p *ParallelImport) reject(c *chunk, r Reject) {
+	p.mu.Lock()
+	c.rejects = append(c.rejects, r)
+	p.number()
+	p.mu.Unlock()
+}
+
+// fail stops the workers with the first error.
+func (p *ParallelImport) fail(err error) {
+	p.mu.Lock()
+	if p.err == nil {
+		p.err = err
+	}
+	p.mu.Unlock()
+	p.cancel()
+}
+
+func (p *ParallelImport) closeFiles() {
+	for _, f := range p.files {
+		f.Close()
+	}
+	p.files = nil
+}
+
+// Total returns the bytes of the input files, the compressed bytes if they are compressed.
+func (p *ParallelImport) Total() int64 {
+	return p.total
+}
+
+// Cancel stops the workers, the rows that are appended are kept.
+func (p *ParallelImport) Cancel() {
+	p.cancel()
+}
+
+// Wait waits for the import to finish up to the timeout in milliseconds,
+// and returns the progress with the rejects since the last call.
+func (p *ParallelImport) Wait(timeout int) *ImportStatus {
+	select {
+	case <-p.done:
+	case <-time.After(time.Duration(timeout) * time.Millisecond):
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ret := &ImportStatus{
+		Total:   p.total,
+		Offset:  p.offset.Load(),
+		Lines:   p.lines.Load(),
+		Rows:    p.rows.Load(),
+		Rejects: p.rejects,
+	}
+	p.rejects = []Reject{}
+	select {
+	case <-p.done:
+		ret.Done = true
+		if p.err != nil {
+			ret.Error = p.err.Error()
+		}
+	default:
+	}
+	return ret
+}
+
+// Results returns the results of the workers after the import is done.
+func (p *ParallelImport) Results() []WorkerResult {
+	<-p.done
+	return p.results
+}
@@ -0,0 +1,109 @@
+package machcli
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/machbase/jsh/engine"
+	"github.com/machbase/neo-server/v8/api"
+	"github.com/machbase/neo-shell/internal/vfs"
+)
+
+func TestParallelImport(t *testing.T) {
+	dir := t.TempDir()
+	expectRejects := map[string]string{}
+	// a compressed file is imported by a worker as a whole
+	for n, name := range []string{"a.csv", "b.csv.gz"} {
+		lines := []string{"time,VALUE,name"}
+		for i := 0; i < 500; i++ {
+			line := fmt.Sprintf("%d,%d.5,tag%d", 1700000000000+n*1000+i, i, n)
+			switch i {
+			case 100:
+				line = fmt.Sprintf("%d,bad,tag%d", 1700000000000+n*1000+i, n)
+				expectRejects[fmt.Sprintf("/work/%s:%d", name, i+2)] = "column VALUE: invalid float"
+			case 333:
+				line = fmt.Sprintf("%d,1", 1700000000000+n*1000+i)
+				expectRejects[fmt.Sprintf("/work/%s:%d", name, i+2)] = "expected 3 fields, got 2"
+			}
+			lines = append(lines, line)
+		}
+		data := []byte(strings.Join(lines, "\n"))
+		if strings.HasSuffix(name, ".gz") {
+			buf := &bytes.Buffer{}
+			zw := gzip.NewWriter(buf)
+			zw.Write(data)
+			zw.Close()
+			data = buf.Bytes()
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vfs.Mount(engine.FSTabs{{MountPoint: "/work", Source: dir}})
+	defer vfs.Mount(nil)
+
+	mu := sync.Mutex{}
+	appenders := []*testAppender{}
+	open := func() (api.Appender, func() error, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		app := &testAppender{
+			tableType: api.TableTypeTag,
+			columns:   testColumns("NAME", api.ColumnTypeVarchar, "TIME", api.ColumnTypeDatetime, "VALUE", api.ColumnTypeDouble),
+		}
+		appenders = append(appenders, app)
+		return app, func() error { return nil }, nil
+	}
+	p, err := startParallelImport([]string{"/work/*.csv*"}, ParallelOption{Workers: 4, Header: "columns", Timeformat: "ms", NullValue: "NULL"}, open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejects := []Reject{}
+	var status *ImportStatus
+	for status == nil || !status.Done {
+		status = p.Wait(100)
+		rejects = append(rejects, status.Rejects...)
+	}
+	if status.Error != "" {
+		t.Fatal(status.Error)
+	}
+	var size int64
+	for _, name := range []string{"a.csv", "b.csv.gz"} {
+		fi, _ := os.Stat(filepath.Join(dir, name))
+		size += fi.Size()
+	}
+	if status.Total != size || status.Offset != size || status.Lines != 1002 || status.Rows != 996 {
+		t.Fatalf("expected %d bytes, 1002 lines and 996 rows, got %+v", size, status)
+	}
+	results := p.Results()
+	if len(results) != 3 || len(appenders) != 3 {
+		t.Fatalf("expected 3 workers, got %d results, %d appenders", len(results), len(appenders))
+	}
+	values := []string{}
+	var success int64
+	for i, r := range results {
+		success += r.Success
+		for _, row := range appenders[i].rows {
+			values = append(values, fmt.Sprintf("%s/%v", row[0], row[2]))
+		}
+	}
+	if success != 996 || len(values) != 996 || !slices.Contains(values, "tag1/499.5") || !slices.Contains(values, "tag0/0.5") {
+		t.Fatalf("expected 996 rows, got %d success, %d rows", success, len(values))
+	}
+
+	if len(rejects) != len(expectRejects) {
+		t.Fatalf("expected %d rejects, got %+v", len(expectRejects), rejects)
+	}
+	for _, rej := range rejects {
+		if want, ok := expectRejects[fmt.Sprintf("%s:%d", rej.Path, rej.Line)]; !ok || !strings.HasPrefix(rej.Error, want) {
+			t.Errorf("unexpected reject %+v", rej)
+		}
+	}
+}
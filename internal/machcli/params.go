@@ -0,0 +1,128 @@
+package machcli
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NamedQuery is the statement of which :name placeholders are replaced with '?'
+// and the parameters to bind in the order of the placeholders.
+type NamedQuery struct {
+	Text   string `json:"text"`
+	Params []any  `json:"params"`
+}
+
+// BindNamed replaces the :name placeholders of the statement with '?' and binds the variables of the names.
+// The placeholders in the string literals, the quoted identifiers and the comments are kept as they are.
+// The string variables are converted by ParamValue.
+func BindNamed(sqlText string, vars map[string]any) (*NamedQuery, error) {
+	ret := &NamedQuery{Params: []any{}}
+	out := strings.Builder{}
+	positional := false
+	for i := 0; i < len(sqlText); {
+		c := sqlText[i]
+		switch {
+		case c == '\'' || c == '"':
+			// string literal or quoted identifier, the quote is escaped by doubling it
+			j := i + 1
+			for j < len(sqlText) {
+				if sqlText[j] == c {
+					if j+1 < len(sqlText) && sqlText[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			j = min(j+1, len(sqlText))
+			out.WriteString(sqlText[i:j])
+			i = j
+		case c == '-' && strings.HasPrefix(sqlText[i:], "--"):
+			j := strings.IndexByte(sqlText[i:], '\n')
+			if j < 0 {
+				j = len(sqlText) - i
+			}
+			out.WriteString(sqlText[i : i+j])
+			i += j
+		case c == '/' && strings.HasPrefix(sqlText[i:], "/*"):
+			j := strings.Index(sqlText[i+2:], "*/")
+			if j < 0 {
+				j = len(sqlText) - i
+			} else {
+				j += 4
+			}
+			out.WriteString(sqlText[i : i+j])
+			i += j
+		case c == '?':
+			positional = true
+			out.WriteByte(c)
+			i++
+		case c == ':' && i+1 < len(sqlText) && isNameStart(sqlText[i+1]) && (i == 0 || sqlText[i-1] != ':'):
+			j := i + 2
+			for j < len(sqlText) && isNamePart(sqlText[j]) {
+				j++
+			}
+			name := sqlText[i+1 : j]
+			v, ok := vars[name]
+			if !ok {
+				return nil, fmt.Errorf("variable '%s' is not set", name)
+			}
+			if s, ok := v.(string); ok {
+				v = ParamValue(s)
+			}
+			ret.Params = append(ret.Params, v)
+			out.WriteByte('?')
+			i = j
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	if positional && len(ret.Params) > 0 {
+		return nil, errors.New("'?' and :name parameters can not be used together")
+	}
+	ret.Text = out.String()
+	return ret, nil
+}
+
+// decimal literals that are bound as numbers, e.g. 10, -1.5, 1e3,
+// but not 0010, +1, .5, 0x1p4, nan or inf.
+var (
+	integerLiteral = regexp.MustCompile(`^-?(0|[1-9][0-9]*)$`)
+	floatLiteral   = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+)
+
+// ParamValue returns the value of the text of a variable,
+// an integer or a float number if it is a plain decimal literal, the text in the quotes if it is quoted,
+// otherwise the text as it is.
+func ParamValue(s string) any {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		q := string(s[0])
+		return strings.ReplaceAll(s[1:len(s)-1], q+q, q)
+	}
+	if integerLiteral.MatchString(s) {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+		// out of the range of int64
+		return s
+	}
+	if floatLiteral.MatchString(s) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNamePart(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,94 @@
+package machcli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBindNamed(t *testing.T) {
+	vars := map[string]any{"tag": "sensor-1", "n": "10", "v": "1.5", "s": "'10'", "t": int64(7)}
+	tests := []struct {
+		sql    string
+		text   string
+		params []any
+		err    string
+	}{
+		{
+			sql:    "SELECT * FROM T WHERE NAME = :tag AND VALUE > :v LIMIT :n",
+			text:   "SELECT * FROM T WHERE NAME = ? AND VALUE > ? LIMIT ?",
+			params: []any{"sensor-1", 1.5, int64(10)},
+		},
+		{
+			sql:    "SELECT ':tag', \"a:tag\", 'it''s :tag' FROM T -- :tag\nWHERE X = :s /* :n */ AND Y = :t",
+			text:   "SELECT ':tag', \"a:tag\", 'it''s :tag' FROM T -- :tag\nWHERE X = ? /* :n */ AND Y = ?",
+			params: []any{"10", int64(7)},
+		},
+		{
+			sql:    "SELECT * FROM T WHERE TIME > '2025-01-01 00:00:00' AND A = ?",
+			text:   "SELECT * FROM T WHERE TIME > '2025-01-01 00:00:00' AND A = ?",
+			params: []any{},
+		},
+		{
+			sql: "SELECT * FROM T WHERE NAME = :missing",
+			err: "variable 'missing' is not set",
+		},
+		{
+			sql: "SELECT * FROM T WHERE NAME = :tag AND A = ?",
+			err: "'?' and :name parameters can not be used together",
+		},
+	}
+	for _, tt := range tests {
+		q, err := BindNamed(tt.sql, vars)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%q: expected error %q, got %v", tt.sql, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", tt.sql, err)
+		}
+		if q.Text != tt.text {
+			t.Errorf("expected %q, got %q", tt.text, q.Text)
+		}
+		if !reflect.DeepEqual(q.Params, tt.params) {
+			t.Errorf("%q: expected params %#v, got %#v", tt.sql, tt.params, q.Params)
+		}
+	}
+}
+
+func TestParamValue(t *testing.T) {
+	tests := []struct {
+		s    string
+		want any
+	}{
+		{"10", int64(10)},
+		{"-7", int64(-7)},
+		{"0", int64(0)},
+		{"1.5", 1.5},
+		{"-0.25", -0.25},
+		{"1e3", 1000.0},
+		{"2.5E-1", 0.25},
+		{"'10'", "10"},
+		{`"it""s"`, `it"s`},
+		{"0001", "0001"},
+		{"+1", "+1"},
+		{".5", ".5"},
+		{"1.", "1."},
+		{"0x1p4", "0x1p4"},
+		{"0x10", "0x10"},
+		{"1_000", "1_000"},
+		{"nan", "nan"},
+		{"NaN", "NaN"},
+		{"inf", "inf"},
+		{"-Infinity", "-Infinity"},
+		{"99999999999999999999", "99999999999999999999"},
+		{"sensor-1", "sensor-1"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := ParamValue(tt.s); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: expected %#v, got %#v", tt.s, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,624 @@
+package machcli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/machbase/neo-server/v8/api"
+	"github.com/machbase/neo-shell/internal/vfs"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/deprecated"
+	"github.com/parquet-go/parquet-go/format"
+)
+
+// kinds of the source values,
+// a source yields int64, uint64, float64, bool, string, []byte, time.Time or nil.
+const (
+	kindInt    = "int"
+	kindUint   = "uint"
+	kindFloat  = "float"
+	kindBool   = "bool"
+	kindString = "string"
+	kindBinary = "binary"
+	kindTime   = "time"
+)
+
+// SourceColumn is a column of the file to import.
+type SourceColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // type name of the file format
+	kind string
+}
+
+// recordSource reads the rows of a columnar file.
+type recordSource interface {
+	Columns() []SourceColumn
+	// NumRows returns the number of rows, -1 if it is unknown.
+	NumRows() int64
+	// Read fills the rows in the order of Columns() and returns io.EOF at the end.
+	Read(rows [][]any) (int, error)
+	Close() error
+}
+
+type target struct {
+	src  int // index of the source column, -1 if the column is not in the file
+	name string
+	conv func(any) (any, error)
+}
+
+// RecordReader appends the rows of a parquet or arrow IPC file to a table.
+// The values are converted to the types of the columns in Go,
+// so that they do not lose the precision through the script.
+type RecordReader struct {
+	path     string
+	src      recordSource
+	appender api.Appender
+	targets  []target
+	rows     [][]any
+	nrow     int64
+}
+
+// NewRecordReader opens the file of the format, "parquet" or "arrow".
+func NewRecordReader(format string, path string) (*RecordReader, error) {
+	f, size, err := vfs.OpenFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var src recordSource
+	switch strings.ToLower(format) {
+	case "parquet":
+		src, err = newParquetSource(f, size)
+	case "arrow", "ipc", "feather":
+		src, err = newArrowSource(f)
+	default:
+		err = fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &RecordReader{path: path, src: src}, nil
+}
+
+func (r *RecordReader) Columns() []SourceColumn {
+	return r.src.Columns()
+}
+
+func (r *RecordReader) NumRows() int64 {
+	return r.src.NumRows()
+}
+
+// Count reads the rows without appending and returns the number of rows,
+// for the file that does not know the number of rows, e.g. arrow IPC.
+func (r *RecordReader) Count() (int64, error) {
+	if n := r.src.NumRows(); n >= 0 {
+		return n, nil
+	}
+	rows := make([][]any, 1000)
+	for i := range rows {
+		rows[i] = make([]any, len(r.src.Columns()))
+	}
+	var total int64
+	for {
+		n, err := r.src.Read(rows)
+		total += int64(n)
+		if err == io.EOF {
+			return total, nil
+		} else if err != nil {
+			return total, err
+		}
+	}
+}
+
+func (r *RecordReader) Close() error {
+	return r.src.Close()
+}
+
+// Bind maps the columns of the file to the columns of the appender by name.
+// It reports all the columns that can not be imported at once,
+// before any row is appended.
+func (r *RecordReader) Bind(appender api.Appender) error {
+	cols, err := appender.Columns()
+	if err != nil {
+		return err
+	}
+	srcCols := r.src.Columns()
+	used := make([]bool, len(srcCols))
+	targets := []target{}
+	problems := []string{}
+	for i, col := range cols {
+		idx := slices.IndexFunc(srcCols, func(c SourceColumn) bool { return strings.EqualFold(c.Name, col.Name) })
+		if idx < 0 {
+			// the appender fills _ARRIVAL_TIME of a log table when it is omitted
+			if i == 0 && col.Name == "_ARRIVAL_TIME" {
+				continue
+			}
+			targets = append(targets, target{src: -1, name: col.Name})
+			continue
+		}
+		used[idx] = true
+		conv, err := converter(srcCols[idx], col)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("column %s: %s", col.Name, err.Error()))
+			continue
+		}
+		targets = append(targets, target{src: idx, name: col.Name, conv: conv})
+	}
+	for i, c := range srcCols {
+		if !used[i] {
+			problems = append(problems, fmt.Sprintf("column %s: not found in table %s", c.Name, appender.TableName()))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s can not be imported into %s\n  %s", r.path, appender.TableName(), strings.Join(problems, "\n  "))
+	}
+	r.appender = appender
+	r.targets = targets
+	return nil
+}
+
+// Append appends up to n rows and returns the number of rows appended,
+// it returns 0 when all rows are appended.
+func (r *RecordReader) Append(n int) (int, error) {
+	if r.appender == nil {
+		return 0, errors.New("reader is not bound to an appender")
+	}
+	if n <= 0 {
+		n = 1000
+	}
+	ncols := len(r.src.Columns())
+	for len(r.rows) < n {
+		r.rows = append(r.rows, make([]any, ncols))
+	}
+	cnt, err := r.src.Read(r.rows[:n])
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	for _, row := range r.rows[:cnt] {
+		r.nrow++
+		values := make([]any, len(r.targets))
+		for i, t := range r.targets {
+			if t.src < 0 || row[t.src] == nil {
+				continue
+			}
+			v, err := t.conv(row[t.src])
+			if err != nil {
+				return 0, fmt.Errorf("row %d column %s: %w", r.nrow, t.name, err)
+			}
+			values[i] = v
+		}
+		if err := r.appender.Append(values...); err != nil {
+			return 0, fmt.Errorf("row %d: %w", r.nrow, err)
+		}
+	}
+	return cnt, nil
+}
+
+// converter returns the function that converts the values of the source column
+// to the Go type that the appender requires for the column.
+func converter(src SourceColumn, col *api.Column) (func(any) (any, error), error) {
+	accept := func(kinds ...string) bool { return slices.Contains(kinds, src.kind) }
+	var conv func(any) (any, error)
+	switch col.Type {
+	case api.ColumnTypeShort:
+		conv = integerConverter(16, true)
+	case api.ColumnTypeUShort:
+		conv = integerConverter(16, false)
+	case api.ColumnTypeInteger:
+		conv = integerConverter(32, true)
+	case api.ColumnTypeUInteger:
+		conv = integerConverter(32, false)
+	case api.ColumnTypeLong:
+		conv = integerConverter(64, true)
+	case api.ColumnTypeULong:
+		conv = integerConverter(64, false)
+	case api.ColumnTypeFloat, api.ColumnTypeDouble:
+		if !accept(kindInt, kindUint, kindFloat) {
+			break
+		}
+		double := col.Type == api.ColumnTypeDouble
+		return func(v any) (any, error) {
+			var f float64
+			switch x := v.(type) {
+			case int64:
+				f = float64(x)
+			case uint64:
+				f = float64(x)
+			case float64:
+				f = x
+			}
+			if double {
+				return f, nil
+			}
+			return float32(f), nil
+		}, nil
+	case api.ColumnTypeDatetime:
+		if accept(kindTime) {
+			return func(v any) (any, error) { return v, nil }, nil
+		}
+	case api.ColumnTypeVarchar, api.ColumnTypeText, api.ColumnTypeClob, api.ColumnTypeJSON,
+		api.ColumnTypeIPv4, api.ColumnTypeIPv6:
+		if accept(kindString, kindBinary) {
+			return func(v any) (any, error) {
+				if b, ok := v.([]byte); ok {
+					return string(b), nil
+				}
+				return v, nil
+			}, nil
+		}
+	case api.ColumnTypeBinary, api.ColumnTypeBlob:
+		if accept(kindString, kindBinary) {
+			return func(v any) (any, error) {
+				if s, ok := v.(string); ok {
+					return []byte(s), nil
+				}
+				return v, nil
+			}, nil
+		}
+	}
+	if conv != nil && accept(kindInt, kindUint) {
+		return conv, nil
+	}
+	return nil, fmt.Errorf("%s can not be converted to %s", src.Type, col.Type.String())
+}
+
+// integerConverter converts the integers to the Go type of the bits,
+// the values out of the range are errors.
+func integerConverter(bits int, signed bool) func(any) (any, error) {
+	var min int64
+	var max uint64
+	if signed {
+		min, max = -1<<(bits-1), 1<<(bits-1)-1
+	} else {
+		max = math.MaxUint64 >> (64 - bits)
+	}
+	return func(v any) (any, error) {
+		var n int64
+		switch x := v.(type) {
+		case int64:
+			if x < min || (x > 0 && uint64(x) > max) {
+				return nil, fmt.Errorf("value %d is out of range", x)
+			}
+			n = x
+		case uint64:
+			if x > max {
+				return nil, fmt.Errorf("value %d is out of range", x)
+			}
+			if !signed && bits == 64 {
+				return x, nil
+			}
+			n = int64(x)
+		default:
+			return nil, fmt.Errorf("invalid integer value %v (%T)", v, v)
+		}
+		switch {
+		case signed && bits == 16:
+			return int16(n), nil
+		case signed && bits == 32:
+			return int32(n), nil
+		case signed:
+			return n, nil
+		case bits == 16:
+			return uint16(n), nil
+		case bits == 32:
+			return uint32(n), nil
+		default:
+			return uint64(n), nil
+		}
+	}
+}
+
+type parquetSource struct {
+	file    io.Closer
+	pf      *parquet.File
+	r       *parquet.Reader
+	cols    []SourceColumn
+	decodes []func(parquet.Value) any
+	buf     []parquet.Row
+}
+
+func newParquetSource(f vfs.File, size int64) (*parquetSource, error) {
+	pf, err := parquet.OpenFile(f, size)
+	if err != nil {
+		return nil, err
+	}
+	ret := &parquetSource{file: f, pf: pf}
+	for _, field := range pf.Schema().Fields() {
+		if !field.Leaf() || field.Repeated() {
+			return nil, fmt.Errorf("column %s: nested or repeated columns are not supported", field.Name())
+		}
+		col, decode := parquetColumn(field)
+		ret.cols = append(ret.cols, col)
+		ret.decodes = append(ret.decodes, decode)
+	}
+	ret.r = parquet.NewReader(pf)
+	return ret, nil
+}
+
+func parquetColumn(field parquet.Field) (col SourceColumn, decode func(parquet.Value) any) {
+	typ := field.Type()
+	col = SourceColumn{Name: field.Name(), Type: typ.String()}
+	ints := func(signed bool) {
+		col.kind = kindInt
+		if !signed {
+			col.kind = kindUint
+		}
+		decode = func(v parquet.Value) any {
+			switch {
+			case typ.Kind() == parquet.Int32 && signed:
+				return int64(v.Int32())
+			case typ.Kind() == parquet.Int32:
+				return uint64(v.Uint32())
+			case signed:
+				return v.Int64()
+			default:
+				return v.Uint64()
+			}
+		}
+	}
+	timestamp := func(unit time.Duration) {
+		col.kind = kindTime
+		decode = func(v parquet.Value) any { return time.Unix(0, v.Int64()*int64(unit)) }
+	}
+	date := func() {
+		col.kind = kindTime
+		decode = func(v parquet.Value) any { return time.Unix(int64(v.Int32())*86400, 0) }
+	}
+	text := func() {
+		col.kind = kindString
+		decode = func(v parquet.Value) any { return string(v.ByteArray()) }
+	}
+
+	if lt := typ.LogicalType(); lt != nil {
+		col.Type = lt.String()
+		switch x := lt.Value.(type) {
+		case *format.TimestampType:
+			timestamp(x.Unit.Value.Duration())
+		case *format.DateType:
+			date()
+		case *format.StringType, *format.EnumType, *format.JsonType:
+			text()
+		case *format.IntType:
+			ints(x.IsSigned)
+		}
+		return
+	}
+	if ct := typ.ConvertedType(); ct != nil {
+		switch *ct {
+		case deprecated.TimestampMillis:
+			timestamp(time.Millisecond)
+		case deprecated.TimestampMicros:
+			timestamp(time.Microsecond)
+		case deprecated.Date:
+			date()
+		case deprecated.UTF8, deprecated.Enum, deprecated.Json:
+			text()
+		case deprecated.Int8, deprecated.Int16, deprecated.Int32, deprecated.Int64:
+			ints(true)
+		case deprecated.Uint8, deprecated.Uint16, deprecated.Uint32, deprecated.Uint64:
+			ints(false)
+		}
+		return
+	}
+	switch typ.Kind() {
+	case parquet.Boolean:
+		col.kind = kindBool
+		decode = func(v parquet.Value) any { return v.Boolean() }
+	case parquet.Int32, parquet.Int64:
+		ints(true)
+	case parquet.Int96:
+		// legacy timestamp, nanoseconds of the day and the julian day
+		col.kind = kindTime
+		decode = func(v parquet.Value) any {
+			i96 := v.Int96()
+			nanos := int64(i96[1])<<32 | int64(i96[0])
+			days := int64(i96[2]) - 2440588
+			return time.Unix(days*86400, nanos)
+		}
+	case parquet.Float:
+		col.kind = kindFloat
+		decode = func(v parquet.Value) any { return float64(v.Float()) }
+	case parquet.Double:
+		col.kind = kindFloat
+		decode = func(v parquet.Value) any { return v.Double() }
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		col.kind = kindBinary
+		decode = func(v parquet.Value) any { return bytes.Clone(v.ByteArray()) }
+	}
+	return
+}
+
+func (s *parquetSource) Columns() []SourceColumn { return s.cols }
+
+func (s *parquetSource) NumRows() int64 { return s.pf.NumRows() }
+
+func (s *parquetSource) Read(rows [][]any) (int, error) {
+	if len(s.buf) < len(rows) {
+		s.buf = make([]parquet.Row, len(rows))
+	}
+	n, err := s.r.ReadRows(s.buf[:len(rows)])
+	for i := 0; i < n; i++ {
+		clear(rows[i])
+		for _, v := range s.buf[i] {
+			c := v.Column()
+			if v.IsNull() || c < 0 || c >= len(s.decodes) || s.decodes[c] == nil {
+				continue
+			}
+			rows[i][c] = s.decodes[c](v)
+		}
+	}
+	return n, err
+}
+
+func (s *parquetSource) Close() error {
+	s.r.Close()
+	return s.file.Close()
+}
+
+// arrowSource reads the arrow IPC file format, or the stream format.
+type arrowSource struct {
+	file io.Closer
+	fr   *ipc.FileReader
+	sr   *ipc.Reader
+	next int // index of the next record batch of the file format
+	rec  arrow.RecordBatch
+	off  int
+	cols []SourceColumn
+}
+
+func newArrowSource(f vfs.File) (*arrowSource, error) {
+	ret := &arrowSource{file: f}
+	var schema *arrow.Schema
+	if fr, err := ipc.NewFileReader(f); err == nil {
+		ret.fr, schema = fr, fr.Schema()
+	} else {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		sr, err := ipc.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		ret.sr, schema = sr, sr.Schema()
+	}
+	for _, field := range schema.Fields() {
+		ret.cols = append(ret.cols, SourceColumn{Name: field.Name, Type: field.Type.String(), kind: arrowKind(field.Type)})
+	}
+	return ret, nil
+}
+
+func arrowKind(dt arrow.DataType) string {
+	switch dt.ID() {
+	case arrow.INT8, arrow.INT16, arrow.INT32, arrow.INT64:
+		return kindInt
+	case arrow.UINT8, arrow.UINT16, arrow.UINT32, arrow.UINT64:
+		return kindUint
+	case arrow.FLOAT32, arrow.FLOAT64:
+		return kindFloat
+	case arrow.BOOL:
+		return kindBool
+	case arrow.STRING, arrow.LARGE_STRING:
+		return kindString
+	case arrow.BINARY, arrow.LARGE_BINARY, arrow.FIXED_SIZE_BINARY:
+		return kindBinary
+	case arrow.TIMESTAMP, arrow.DATE32, arrow.DATE64:
+		return kindTime
+	default:
+		return ""
+	}
+}
+
+func arrowValue(arr arrow.Array, i int) any {
+	if arr.IsNull(i) {
+		return nil
+	}
+	switch a := arr.(type) {
+	case *array.Int8:
+		return int64(a.Value(i))
+	case *array.Int16:
+		return int64(a.Value(i))
+	case *array.Int32:
+		return int64(a.Value(i))
+	case *array.Int64:
+		return a.Value(i)
+	case *array.Uint8:
+		return uint64(a.Value(i))
+	case *array.Uint16:
+		return uint64(a.Value(i))
+	case *array.Uint32:
+		return uint64(a.Value(i))
+	case *array.Uint64:
+		return a.Value(i)
+	case *array.Float32:
+		return float64(a.Value(i))
+	case *array.Float64:
+		return a.Value(i)
+	case *array.Boolean:
+		return a.Value(i)
+	case *array.String:
+		return strings.Clone(a.Value(i))
+	case *array.LargeString:
+		return strings.Clone(a.Value(i))
+	case *array.Binary:
+		return bytes.Clone(a.Value(i))
+	case *array.LargeBinary:
+		return bytes.Clone(a.Value(i))
+	case *array.FixedSizeBinary:
+		return bytes.Clone(a.Value(i))
+	case *array.Timestamp:
+		return a.Value(i).ToTime(a.DataType().(*arrow.TimestampType).Unit)
+	case *array.Date32:
+		return a.Value(i).ToTime()
+	case *array.Date64:
+		return a.Value(i).ToTime()
+	default:
+		return nil
+	}
+}
+
+func (s *arrowSource) Columns() []SourceColumn { return s.cols }
+
+func (s *arrowSource) NumRows() int64 { return -1 }
+
+// nextRecord moves to the next record batch,
+// the record batch is owned by the reader and valid until the next call.
+func (s *arrowSource) nextRecord() error {
+	s.off = 0
+	if s.fr != nil {
+		if s.next >= s.fr.NumRecords() {
+			return io.EOF
+		}
+		rec, err := s.fr.RecordBatch(s.next)
+		if err != nil {
+			return err
+		}
+		s.next++
+		s.rec = rec
+		return nil
+	}
+	if !s.sr.Next() {
+		if err := s.sr.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	s.rec = s.sr.RecordBatch()
+	return nil
+}
+
+func (s *arrowSource) Read(rows [][]any) (int, error) {
+	n := 0
+	for n < len(rows) {
+		if s.rec == nil || s.off >= int(s.rec.NumRows()) {
+			if err := s.nextRecord(); err != nil {
+				return n, err
+			}
+			continue
+		}
+		for c := range s.cols {
+			rows[n][c] = arrowValue(s.rec.Column(c), s.off)
+		}
+		s.off++
+		n++
+	}
+	return n, nil
+}
+
+func (s *arrowSource) Close() error {
+	if s.fr != nil {
+		s.fr.Close()
+	}
+	if s.sr != nil {
+		s.sr.Release()
+	}
+	return s.file.Close()
+}
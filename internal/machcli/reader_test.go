@@ -0,0 +1,238 @@
+package machcli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/machbase/jsh/engine"
+	"github.com/machbase/neo-server/v8/api"
+	"github.com/machbase/neo-shell/internal/vfs"
+	"github.com/parquet-go/parquet-go"
+)
+
+type testAppender struct {
+	tableType api.TableType
+	columns   api.Columns
+	rows      [][]any
+}
+
+func (a *testAppender) TableName() string                               { return "EXAMPLE" }
+func (a *testAppender) AppendLogTime(ts time.Time, values ...any) error { return nil }
+func (a *testAppender) Close() (int64, int64, error)                    { return int64(len(a.rows)), 0, nil }
+func (a *testAppender) Columns() (api.Columns, error)                   { return a.columns, nil }
+func (a *testAppender) TableType() api.TableType                        { return a.tableType }
+func (a *testAppender) WithInputColumns(columns ...string) api.Appender { return a }
+func (a *testAppender) Append(values ...any) error                      { a.rows = append(a.rows, values); return nil }
+
+func testColumns(defs ...any) api.Columns {
+	ret := api.Columns{}
+	for i := 0; i < len(defs); i += 2 {
+		ret = append(ret, &api.Column{Name: defs[i].(string), Type: defs[i+1].(api.ColumnType)})
+	}
+	return ret
+}
+
+func importAll(t *testing.T, format string, path string, appender *testAppender) {
+	t.Helper()
+	r, err := NewRecordReader(format, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := r.Bind(appender); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		n, err := r.Append(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+}
+
+func checkRow(t *testing.T, name string, got []any, want []any) {
+	t.Helper()
+	for i, v := range got {
+		if tv, ok := v.(time.Time); ok {
+			got[i] = tv.UTC()
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: expected %#v, got %#v", name, want, got)
+	}
+}
+
+func TestRecordReaderParquet(t *testing.T) {
+	type record struct {
+		Name  string    `parquet:"name"`
+		Time  time.Time `parquet:"time,timestamp(microsecond)"`
+		Value *float64  `parquet:"value,optional"`
+		Count int32     `parquet:"count"`
+		Flags uint8     `parquet:"flags"`
+	}
+	ts := time.Unix(1700000000, 123456000).UTC()
+	value := 3.5
+	records := []record{
+		{Name: "a", Time: ts, Value: &value, Count: 1, Flags: 7},
+		{Name: "b", Time: ts.Add(time.Second), Count: 2},
+		{Name: "c", Time: ts.Add(2 * time.Second), Value: &value, Count: 3},
+	}
+	dir := t.TempDir()
+	if err := parquet.WriteFile(filepath.Join(dir, "data.parquet"), records); err != nil {
+		t.Fatal(err)
+	}
+	vfs.Mount(engine.FSTabs{{MountPoint: "/work", Source: dir}})
+	defer vfs.Mount(nil)
+
+	r, err := NewRecordReader("parquet", "/work/data.parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, cols := r.NumRows(), r.Columns(); n != 3 || len(cols) != 5 {
+		t.Errorf("expected 3 rows and 5 columns, got %d rows and %d columns", n, len(cols))
+	}
+	r.Close()
+
+	// _ARRIVAL_TIME is omitted, MEMO is not in the file
+	appender := &testAppender{
+		tableType: api.TableTypeLog,
+		columns: testColumns("_ARRIVAL_TIME", api.ColumnTypeDatetime, "NAME", api.ColumnTypeVarchar,
+			"TIME", api.ColumnTypeDatetime, "VALUE", api.ColumnTypeDouble, "COUNT", api.ColumnTypeShort,
+			"FLAGS", api.ColumnTypeUInteger, "MEMO", api.ColumnTypeVarchar),
+	}
+	importAll(t, "parquet", "/work/data.parquet", appender)
+	if len(appender.rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(appender.rows))
+	}
+	checkRow(t, "row 1", appender.rows[0], []any{"a", ts, 3.5, int16(1), uint32(7), nil})
+	checkRow(t, "row 2", appender.rows[1], []any{"b", ts.Add(time.Second), nil, int16(2), uint32(0), nil})
+
+	// all mismatches are reported before appending
+	appender = &testAppender{
+		tableType: api.TableTypeTag,
+		columns: testColumns("NAME", api.ColumnTypeLong, "TIME", api.ColumnTypeDouble,
+			"VALUE", api.ColumnTypeDouble, "COUNT", api.ColumnTypeShort),
+	}
+	r, err = NewRecordReader("parquet", "/work/data.parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	err = r.Bind(appender)
+	if err == nil {
+		t.Fatal("expected mismatch error")
+	}
+	for _, want := range []string{
+		"column NAME: STRING can not be converted to long",
+		"column TIME: TIMESTAMP(isAdjustedToUTC=true,unit=MICROS) can not be converted to double",
+		"column flags: not found in table EXAMPLE",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in the error, got %q", want, err.Error())
+		}
+	}
+	if _, err := r.Append(10); err == nil {
+		t.Errorf("expected error for appending without binding")
+	}
+}
+
+func TestRecordReaderArrow(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "NAME", Type: arrow.BinaryTypes.String},
+		{Name: "TIME", Type: &arrow.TimestampType{Unit: arrow.Millisecond}},
+		{Name: "VALUE", Type: arrow.PrimitiveTypes.Float32, Nullable: true},
+		{Name: "SEQ", Type: arrow.PrimitiveTypes.Int64},
+	}, nil)
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	ts := time.UnixMilli(1700000000123).UTC()
+	b.Field(0).(*array.StringBuilder).AppendValues([]string{"a", "b", "c"}, nil)
+	b.Field(1).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{
+		arrow.Timestamp(ts.UnixMilli()), arrow.Timestamp(ts.UnixMilli() + 1), arrow.Timestamp(ts.UnixMilli() + 2)}, nil)
+	b.Field(2).(*array.Float32Builder).AppendValues([]float32{1.5, 0, 2.5}, []bool{true, false, true})
+	b.Field(3).(*array.Int64Builder).AppendValues([]int64{1, 2, 40000}, nil)
+	rec := b.NewRecordBatch()
+	defer rec.Release()
+
+	dir := t.TempDir()
+	// the file format and the stream format, two record batches each
+	for _, name := range []string{"data.arrow", "data.arrows"} {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var w interface {
+			Write(arrow.RecordBatch) error
+			Close() error
+		}
+		if name == "data.arrow" {
+			if w, err = ipc.NewFileWriter(f, ipc.WithSchema(schema)); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			w = ipc.NewWriter(f, ipc.WithSchema(schema))
+		}
+		for i := 0; i < 2; i++ {
+			if err := w.Write(rec); err != nil {
+				t.Fatal(err)
+			}
+		}
+		w.Close()
+		f.Close()
+	}
+	vfs.Mount(engine.FSTabs{{MountPoint: "/work", Source: dir}})
+	defer vfs.Mount(nil)
+
+	for _, name := range []string{"/work/data.arrow", "/work/data.arrows"} {
+		appender := &testAppender{
+			tableType: api.TableTypeTag,
+			columns: testColumns("NAME", api.ColumnTypeVarchar, "TIME", api.ColumnTypeDatetime,
+				"VALUE", api.ColumnTypeFloat, "SEQ", api.ColumnTypeInteger),
+		}
+		importAll(t, "arrow", name, appender)
+		if len(appender.rows) != 6 {
+			t.Fatalf("%s: expected 6 rows, got %d", name, len(appender.rows))
+		}
+		checkRow(t, name, appender.rows[0], []any{"a", ts, float32(1.5), int32(1)})
+		checkRow(t, name, appender.rows[4], []any{"b", ts.Add(time.Millisecond), nil, int32(2)})
+
+		// the number of rows of arrow IPC is known only by reading
+		r, err := NewRecordReader("arrow", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, err := r.Count(); err != nil || n != 6 || r.NumRows() != -1 {
+			t.Errorf("%s: expected 6 rows, got %d %v", name, n, err)
+		}
+		r.Close()
+	}
+
+	// 40000 does not fit in short
+	appender := &testAppender{
+		tableType: api.TableTypeTag,
+		columns: testColumns("NAME", api.ColumnTypeVarchar, "TIME", api.ColumnTypeDatetime,
+			"VALUE", api.ColumnTypeFloat, "SEQ", api.ColumnTypeShort),
+	}
+	r, err := NewRecordReader("arrow", "/work/data.arrow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := r.Bind(appender); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Append(10); err == nil || !strings.HasPrefix(err.Error(), "row 3 column SEQ: value 40000 is out of range") {
+		t.Errorf("expected out of range error, got %v", err)
+	}
+}
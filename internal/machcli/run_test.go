@@ -0,0 +1,270 @@
+package machcli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/machbase/jsh/engine"
+	"github.com/machbase/jsh/native"
+	"github.com/machbase/jsh/root"
+	"github.com/machbase/neo-server/v8/mods/util"
+	"github.com/machbase/neo-shell/internal/pretty"
+	"github.com/machbase/neo-shell/internal/session"
+)
+
+// neoServer mimics the login and the json-rpc api of machbase-neo that the commands use,
+// the mach service is the one of the test server.
+func neoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+			Params []any  `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/web/api/login", "/web/api/relogin":
+			json.NewEncoder(w).Encode(map[string]any{"success": true, "accessToken": "header.e30.sig", "refreshToken": "refresh"})
+		case "/web/api/rpc":
+			rsp := map[string]any{"jsonrpc": "2.0", "id": 1}
+			switch req.Method {
+			case "getServicePorts":
+				rsp["result"] = []map[string]string{{"Service": "mach", "Address": fmt.Sprintf("tcp://127.0.0.1:%d", testServer.MachPort())}}
+			case "splitSqlStatements":
+				text, _ := req.Params[0].(string)
+				stmts, err := util.SplitSqlStatements(strings.NewReader(text))
+				if err != nil {
+					rsp["error"] = map[string]any{"code": -32000, "message": err.Error()}
+				} else {
+					rsp["result"] = stmts
+				}
+			default:
+				rsp["error"] = map[string]any{"code": -32601, "message": "method not found"}
+			}
+			json.NewEncoder(w).Encode(rsp)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(svr.Close)
+	return svr
+}
+
+// runCommand runs the command of /usr/bin with the args as neo-shell does,
+// the files are in /work. It returns the exit code and the output.
+func runCommand(t *testing.T, files map[string]string, args ...string) (int, string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := &bytes.Buffer{}
+	conf := engine.Config{
+		Name: args[0],
+		Args: args,
+		FSTabs: []engine.FSTab{
+			root.RootFSTab(),
+			{MountPoint: "/usr", Source: "../usr/"},
+			{MountPoint: "/work", Source: dir},
+		},
+		Env: map[string]any{
+			"PATH": "/usr/bin:/usr/lib:/sbin:/lib:/work",
+			"HOME": "/work",
+			"PWD":  "/work",
+		},
+		Reader: &bytes.Buffer{},
+		Writer: out,
+	}
+	jr, err := engine.New(conf)
+	if err != nil {
+		t.Fatalf("Failed to create JSRuntime: %v", err)
+	}
+	native.Enable(jr)
+	jr.RegisterNativeModule("@jsh/session", session.Module)
+	jr.RegisterNativeModule("@jsh/machcli", Module)
+	jr.RegisterNativeModule("@jsh/pretty", pretty.Module)
+	return jr.Main(), out.String()
+}
+
+func TestRunCommand(t *testing.T) {
+	svr := neoServer(t)
+	if err := session.Configure(session.Config{
+		Server:   strings.TrimPrefix(svr.URL, "http://"),
+		User:     "sys",
+		Password: "manager",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"ok.sql": strings.Join([]string{
+			"CREATE TAG TABLE IF NOT EXISTS RUN_TAG (NAME VARCHAR(100) primary key, TIME DATETIME basetime, VALUE DOUBLE);",
+			"INSERT INTO RUN_TAG VALUES(:name, TO_DATE('2025-01-01 00:00:00'), :value);",
+			"SELECT NAME, VALUE FROM RUN_TAG;",
+			"DROP TABLE RUN_TAG;",
+		}, "\n"),
+		"rows.sql": strings.Join([]string{
+			"CREATE TABLE RUN_ROWS (ID INTEGER);",
+			"INSERT INTO RUN_ROWS VALUES(1);",
+			"EXPLAIN SELECT * FROM RUN_ROWS;",
+			"SELECT ID FROM RUN_ROWS;",
+			"DROP TABLE RUN_ROWS;",
+		}, "\n"),
+		"fail.sql": strings.Join([]string{
+			"INSERT INTO NO_SUCH_TABLE VALUES(1);",
+			"SELECT NAME FROM M$SYS_USERS WHERE NAME = 'SYS';",
+		}, "\n"),
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		exit   int
+		output []string
+	}{
+		{
+			name: "run_ok",
+			args: []string{"run", "--no-pause", "--param", "name=run", "--param", "value=1.5", "/work/ok.sql"},
+			exit: 0,
+			output: []string{
+				"4 statements, 4 succeeded, 0 failed.",
+			},
+		},
+		{
+			name: "run_rows",
+			args: []string{"run", "--format", "csv", "--no-pause", "/work/rows.sql"},
+			exit: 0,
+			output: []string{
+				"FULL SCAN (RUN_ROWS)",
+				"LINES",
+				"5 statements, 5 succeeded, 0 failed.",
+			},
+		},
+		{
+			name: "run_binary_format",
+			args: []string{"run", "--format", "parquet", "/work/rows.sql"},
+			exit: 1,
+			output: []string{
+				"Invalid --format 'parquet', run prints the rows to the console",
+			},
+		},
+		{
+			name: "run_stop",
+			args: []string{"run", "/work/fail.sql"},
+			exit: 1,
+			output: []string{
+				"NO_SUCH_TABLE",
+				"2 statements, 0 succeeded, 1 failed, 1 skipped.",
+			},
+		},
+		{
+			name: "run_continue",
+			args: []string{"run", "--on-error", "continue", "--no-pause", "/work/fail.sql"},
+			exit: 1,
+			output: []string{
+				"SYS",
+				"2 statements, 1 succeeded, 1 failed.",
+			},
+		},
+		{
+			name: "run_dry_run",
+			args: []string{"run", "--dry-run", "--param", "name=run", "--param", "value=1.5", "/work/ok.sql"},
+			exit: 0,
+			output: []string{
+				"[2/4] Line 2~2: INSERT INTO RUN_TAG VALUES(?, TO_DATE('2025-01-01 00:00:00'), ?)",
+				`    params: "run", 1.5`,
+				"4 statements, 4 checked, 0 failed.",
+			},
+		},
+		{
+			name: "run_unset_variable",
+			args: []string{"run", "--dry-run", "--on-error", "continue", "/work/ok.sql"},
+			exit: 1,
+			output: []string{
+				"    error: variable 'name' is not set",
+				"4 statements, 3 checked, 1 failed.",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			exit, output := runCommand(t, files, tc.args...)
+			if exit != tc.exit {
+				t.Errorf("expected exit code %d, got %d\n%s", tc.exit, exit, output)
+			}
+			for _, s := range tc.output {
+				if !strings.Contains(output, s) {
+					t.Errorf("expected output to contain %q\n%s", s, output)
+				}
+			}
+		})
+	}
+}
+
+// TestInsertReplay replays the statements of the insert format written in a time zone
+// by the session of another time zone, the datetime values should not be shifted.
+func TestInsertReplay(t *testing.T) {
+	var buf bytes.Buffer
+	tbl, err := pretty.Table(pretty.TableOption{Format: "insert", Table: "REPLAY_T", Tz: "Asia/Seoul", Header: true, Precision: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tbl.(*pretty.TableWriter)
+	tw.SetOutput(map[string]any{"writer": &buf})
+	tw.SetPreamble("CREATE TABLE REPLAY_T (NAME varchar(40), TIME datetime, VALUE double)")
+	tw.AppendHeader(table.Row{"NAME", "TIME", "VALUE"})
+	tw.SetColumnTypes([]string{"string", "datetime", "double"})
+	ts := time.Date(2025, 1, 1, 15, 0, 0, 123456789, time.UTC)
+	tw.Append([]any{"it's", ts, 1.5})
+	if _, err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := NewDatabase(fmt.Sprintf(`{"host":"127.0.0.1","port":%d,"user":"sys","password":"manager"}`, testServer.MachPort()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	conn, err := db.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx := context.Background()
+	for _, stmt := range strings.Split(strings.TrimSpace(buf.String()), ";\n") {
+		if rs := conn.Exec(ctx, strings.TrimSuffix(stmt, ";")); rs.Err() != nil {
+			t.Fatalf("%s: %v", stmt, rs.Err())
+		}
+	}
+	defer conn.Exec(ctx, "DROP TABLE REPLAY_T")
+
+	rows, err := conn.Query(ctx, "SELECT NAME, TIME, VALUE FROM REPLAY_T")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		t.Fatal("no rows replayed")
+	}
+	var name string
+	var got time.Time
+	var value float64
+	if err := rows.Scan(&name, &got, &value); err != nil {
+		t.Fatal(err)
+	}
+	if name != "it's" || !got.Equal(ts) || value != 1.5 {
+		t.Errorf("unexpected row %q %v %v, expected time %v", name, got, value, ts)
+	}
+}
@@ -0,0 +1,92 @@
+package machcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// QueryContext is the context of a statement, it is done when the database is cancelled
+// or the timeout of the statement expires.
+type QueryContext struct {
+	Ctx     context.Context
+	cancel  context.CancelFunc
+	start   time.Time
+	timeout time.Duration
+	stop    func() // stops the watcher of the statement, see Database.Watch
+}
+
+// NewQueryContext returns the context of a statement that starts now,
+// it has the deadline of the timeout of the database if the timeout is set.
+func (db *Database) NewQueryContext() *QueryContext {
+	qc := &QueryContext{start: time.Now(), timeout: db.timeout}
+	if db.timeout > 0 {
+		qc.Ctx, qc.cancel = context.WithTimeout(db.Ctx, db.timeout)
+	} else {
+		qc.Ctx, qc.cancel = context.WithCancel(db.Ctx)
+	}
+	return qc
+}
+
+// Timeout returns the timeout of the statements of the database, zero is no timeout.
+func (db *Database) Timeout() time.Duration {
+	return db.timeout
+}
+
+// SetTimeout sets the timeout of the statements that start after it, zero is no timeout.
+func (db *Database) SetTimeout(timeout string) error {
+	d, err := parseTimeout(timeout)
+	if err != nil {
+		return err
+	}
+	db.timeout = d
+	return nil
+}
+
+func parseTimeout(s string) (time.Duration, error) {
+	if s == "" || s == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid timeout '%s'", s)
+	}
+	return d, nil
+}
+
+// Close releases the timer of the context, the statement is not cancelled after it.
+func (qc *QueryContext) Close() {
+	if qc.stop != nil {
+		qc.stop()
+		qc.stop = nil
+	}
+	qc.cancel()
+}
+
+// Done reports whether the statement should stop.
+func (qc *QueryContext) Done() bool {
+	return qc.Ctx.Err() != nil
+}
+
+// TimedOut reports whether the timeout of the statement is expired.
+func (qc *QueryContext) TimedOut() bool {
+	return errors.Is(qc.Ctx.Err(), context.DeadlineExceeded)
+}
+
+// Elapsed returns the time since the statement started.
+func (qc *QueryContext) Elapsed() time.Duration {
+	return time.Since(qc.start)
+}
+
+// Reason returns the message of why the statement stopped, it is empty if the context is not done.
+func (qc *QueryContext) Reason() string {
+	switch {
+	case qc.TimedOut():
+		return fmt.Sprintf("query timeout %s, %s elapsed", qc.timeout, qc.Elapsed().Round(time.Millisecond))
+	case qc.Done():
+		return "query cancelled"
+	default:
+		return ""
+	}
+}
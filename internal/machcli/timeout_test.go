@@ -0,0 +1,57 @@
+package machcli
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryContext(t *testing.T) {
+	db := &Database{}
+	db.Ctx, db.Cancel = context.WithCancel(context.Background())
+	defer db.Cancel()
+
+	if err := db.SetTimeout("50ms"); err != nil {
+		t.Fatal(err)
+	}
+	qc := db.NewQueryContext()
+	defer qc.Close()
+	if qc.Done() || qc.Reason() != "" {
+		t.Fatalf("context should not be done yet, reason %q", qc.Reason())
+	}
+	select {
+	case <-qc.Ctx.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout should expire the context")
+	}
+	if !qc.TimedOut() {
+		t.Errorf("context should be timed out")
+	}
+	if reason := qc.Reason(); !strings.HasPrefix(reason, "query timeout 50ms, ") || !strings.HasSuffix(reason, " elapsed") {
+		t.Errorf("unexpected reason %q", reason)
+	}
+	if qc.Elapsed() < 50*time.Millisecond {
+		t.Errorf("elapsed should be longer than the timeout, got %s", qc.Elapsed())
+	}
+
+	// the next statement has its own deadline
+	if err := db.SetTimeout("0"); err != nil {
+		t.Fatal(err)
+	}
+	qc2 := db.NewQueryContext()
+	defer qc2.Close()
+	if _, ok := qc2.Ctx.Deadline(); ok {
+		t.Errorf("context without timeout should not have a deadline")
+	}
+	db.Cancel()
+	if !qc2.Done() || qc2.TimedOut() || qc2.Reason() != "query cancelled" {
+		t.Errorf("cancelled context, timed out %v reason %q", qc2.TimedOut(), qc2.Reason())
+	}
+
+	for _, s := range []string{"30", "-1s", "abc"} {
+		if err := db.SetTimeout(s); err == nil || err.Error() != "invalid timeout '"+s+"'" {
+			t.Errorf("SetTimeout(%q) expected error, got %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package machcli
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// tlsForwarder relays the plain connections of the native client
+// to the mach port that is served behind a TLS terminator,
+// since the native mach protocol has no TLS layer of its own.
+// It relays only the connection that is expected by expect(),
+// the others are closed so that the local processes can not use the client certificate.
+type tlsForwarder struct {
+	ln     net.Listener
+	target string
+	conf   *tls.Config
+
+	mu       sync.Mutex
+	expected bool
+}
+
+func newTLSForwarder(host string, port int, conf *tls.Config) (*tlsForwarder, error) {
+	if conf.ServerName == "" && !conf.InsecureSkipVerify {
+		conf = conf.Clone()
+		conf.ServerName = host
+	}
+	target := net.JoinHostPort(host, strconv.Itoa(port))
+	// check the TLS handshake in advance to report the error clearly,
+	// otherwise it would be hidden behind the native client's connection error.
+	probe, err := tls.Dial("tcp", target, conf)
+	if err != nil {
+		return nil, fmt.Errorf("tls %s: %w", target, err)
+	}
+	probe.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	ret := &tlsForwarder{ln: ln, target: target, conf: conf}
+	go ret.serve()
+	return ret, nil
+}
+
+// Addr returns the local address that the native client connects to.
+func (f *tlsForwarder) Addr() (string, int) {
+	addr := f.ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+func (f *tlsForwarder) Close() error {
+	return f.ln.Close()
+}
+
+// expect lets the next connection be relayed until the returned function is called,
+// it is called around the connect of the native client.
+func (f *tlsForwarder) expect() func() {
+	f.mu.Lock()
+	f.expected = true
+	f.mu.Unlock()
+	return func() {
+		f.mu.Lock()
+		f.expected = false
+		f.mu.Unlock()
+	}
+}
+
+func (f *tlsForwarder) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		expected := f.expected
+		f.expected = false
+		f.mu.Unlock()
+		if !expected {
+			conn.Close()
+			continue
+		}
+		go f.relay(conn)
+	}
+}
+
+func (f *tlsForwarder) relay(conn net.Conn) {
+	defer conn.Close()
+	remote, err := tls.Dial("tcp", f.target, f.conf)
+	if err != nil {
+		return
+	}
+	defer remote.Close()
+	go func() {
+		io.Copy(remote, conn)
+		remote.CloseWrite()
+	}()
+	io.Copy(conn, remote)
+}
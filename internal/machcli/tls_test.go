@@ -0,0 +1,76 @@
+package machcli
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTLSForwarder(t *testing.T) {
+	// echo server behind TLS, borrowing the self-signed certificate of httptest
+	svr := httptest.NewUnstartedServer(nil)
+	defer svr.Close()
+	svr.StartTLS()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", svr.TLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				fmt.Fprintf(conn, "echo %s", line)
+			}()
+		}
+	}()
+	addr := ln.Addr().(*net.TCPAddr)
+
+	if _, err := newTLSForwarder("127.0.0.1", addr.Port, &tls.Config{}); err == nil {
+		t.Fatalf("forwarder should fail to verify the server certificate")
+	}
+
+	fwd, err := newTLSForwarder("127.0.0.1", addr.Port, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("newTLSForwarder failed: %v", err)
+	}
+	defer fwd.Close()
+
+	host, port := fwd.Addr()
+	echo := func() (string, error) {
+		conn, err := net.Dial("tcp", net.JoinHostPort(host, fmt.Sprint(port)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "hello\n")
+		return bufio.NewReader(conn).ReadString('\n')
+	}
+
+	// the connection that is not expected is not relayed
+	if reply, err := echo(); err == nil {
+		t.Fatalf("unexpected connection should be closed, got %q", reply)
+	}
+
+	done := fwd.expect()
+	reply, err := echo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "echo hello\n" {
+		t.Errorf("unexpected reply %q", reply)
+	}
+	// only one connection is relayed for an expect
+	if reply, err := echo(); err == nil {
+		t.Errorf("second connection should be closed, got %q", reply)
+	}
+	done()
+}
@@ -24,6 +24,8 @@ type TableOption struct {
 	Rownum       bool   `json:"rownum"`
 	NullValue    string `json:"nullValue"`
 	StringEscape bool   `json:"stringEscape"`
+	BlobEncoding string `json:"blobEncoding"` // encoding of binary values in JSON, base64 (default) or hex
+	Table        string `json:"table"`        // table name of the INSERT statements
 }
 
 type TableWriter struct {
@@ -37,12 +39,19 @@ type TableWriter struct {
 	footer       bool
 	pause        bool
 	stringEscape bool
+	blobEncoding string
 	rownum       bool
 	rowCount     int64
 	nullValue    string
 	rawRows      []table.Row // to store raw rows for JSON, NDJSON rendering
 	columnTypes  []string    // to store column types for JSON rendering
 	renderCount  int         // count of render calls
+	jsonRows     int         // count of rows rendered in JSON, to separate the rows of pages
+	parquet      *parquetWriter
+	xlsx         *xlsxWriter
+	table        string // table name of the INSERT statements
+	preamble     string // statements before the INSERT statements
+	err          error  // the first error of rendering, it is reported by Close()
 
 	output               io.Writer
 	nextPauseRow         int64
@@ -63,6 +72,8 @@ func Table(opt TableOption) (table.Writer, error) {
 		rownum:       opt.Rownum,
 		nullValue:    opt.NullValue,
 		stringEscape: opt.StringEscape,
+		blobEncoding: strings.ToUpper(opt.BlobEncoding),
+		table:        opt.Table,
 	}
 	ret.SetBoxStyle(opt.BoxStyle)
 	ret.SetFormat(opt.Format)
@@ -129,10 +140,10 @@ var boxStyles = map[string]BoxStyle{
 func (tw *TableWriter) SetFormat(format string) {
 	tw.format = strings.ToUpper(format)
 	switch tw.format {
-	case "HTML", "JSON":
-		// force disable pause for HTML, JSON formats
+	case "HTML", "JSON", "PARQUET", "XLSX":
+		// force disable pause for HTML, JSON, PARQUET, XLSX formats
 		tw.pause = false
-	case "NDJSON":
+	case "NDJSON", "INSERT":
 		tw.pageHeightSpaceLines = 0
 	case "MD", "MARKDOWN":
 		tw.pageHeightSpaceLines = 2
@@ -275,21 +286,52 @@ func (tw *TableWriter) SetCaption(format string, a ...interface{}) {
 	tw.Writer.SetCaption(format, a...)
 }
 
-func (tw *TableWriter) Close() string {
+func (tw *TableWriter) Close() (string, error) {
+	ret := ""
 	if tw.Writer.Length() > 0 {
 		// remaining rows to render
-		return tw.Render()
-	}
-	if tw.renderCount == 0 {
+		ret = tw.Render()
+	} else if tw.renderCount == 0 {
 		// no rows rendered yet, render empty table
-		return tw.Render()
+		ret = tw.Render()
+	}
+	switch tw.format {
+	case "JSON":
+		ret += tw.closeJSON()
+	case "XML":
+		ret += tw.closeXML()
+	case "YAML":
+		ret += tw.closeYAML()
+	}
+	if tw.parquet != nil {
+		if err := tw.parquet.Close(); err != nil && tw.err == nil {
+			tw.err = err
+		}
+		tw.parquet = nil
+	}
+	if tw.xlsx != nil {
+		if err := tw.xlsx.Close(); err != nil && tw.err == nil {
+			tw.err = err
+		}
+		tw.xlsx = nil
+	}
+	return ret, tw.err
+}
+
+// Abort discards the output that is written by Close(), e.g. the temporary file of xlsx,
+// when the rows are not completed by an error or an interrupt.
+func (tw *TableWriter) Abort() {
+	if tw.xlsx != nil {
+		tw.xlsx.Abort()
+		tw.xlsx = nil
 	}
-	return ""
 }
 
 func (tw *TableWriter) RequirePageRender() bool {
 	if tw.pause {
 		return tw.nextPauseRow > 0 && tw.rowCount == tw.nextPauseRow
+	} else if tw.format == "PARQUET" {
+		return tw.rowCount%parquetRowGroupSize == 0
 	} else {
 		return tw.rowCount%1000 == 0
 	}
@@ -345,26 +387,50 @@ func (tw *TableWriter) ResetRows() {
 	tw.Writer.ResetRows()
 }
 
+// typedFormat returns true if the format keeps the types of values,
+// the null and time values are not converted to strings.
+func (tw *TableWriter) typedFormat() bool {
+	switch tw.format {
+	case "PARQUET", "JSON", "NDJSON", "XLSX", "INSERT", "YAML", "XML":
+		return true
+	default:
+		return false
+	}
+}
+
+// formatTime returns the time in the timeformat,
+// it is a number for the epoch formats (ns, us, ms, s), otherwise a string.
+func (tw *TableWriter) formatTime(val time.Time) any {
+	switch tw.timeformat {
+	case "ns":
+		return val.In(tw.tz).UnixNano()
+	case "us":
+		return val.In(tw.tz).UnixMicro()
+	case "ms":
+		return val.In(tw.tz).UnixMilli()
+	case "s":
+		return val.In(tw.tz).Unix()
+	default:
+		return val.In(tw.tz).Format(tw.timeformat)
+	}
+}
+
 func (tw *TableWriter) Row(values ...interface{}) table.Row {
+	typed := tw.typedFormat()
 	for i, value := range values {
 		if value == nil {
-			values[i] = tw.nullValue
+			if !typed {
+				values[i] = tw.nullValue
+			}
 			continue
 		}
 		switch val := value.(type) {
 		case time.Time:
-			switch tw.timeformat {
-			case "ns":
-				values[i] = val.In(tw.tz).UnixNano()
-			case "us":
-				values[i] = val.In(tw.tz).UnixMicro()
-			case "ms":
-				values[i] = val.In(tw.tz).UnixMilli()
-			case "s":
-				values[i] = val.In(tw.tz).Unix()
-			default:
-				values[i] = val.In(tw.tz).Format(tw.timeformat)
+			if typed {
+				values[i] = val
+				continue
 			}
+			values[i] = tw.formatTime(val)
 		case float32:
 			if tw.precision >= 0 {
 				factor := math.Pow(10, float64(tw.precision))
@@ -417,14 +483,24 @@ func (tw *TableWriter) Render() string {
 		return tw.RenderNDJSON()
 	case "JSON":
 		return tw.RenderJSON()
+	case "PARQUET":
+		return tw.RenderParquet()
+	case "XLSX":
+		return tw.RenderXLSX()
+	case "INSERT":
+		return tw.RenderInsert()
+	case "YAML":
+		return tw.RenderYAML()
+	case "XML":
+		return tw.RenderXML()
 	default:
 		return tw.Writer.Render()
 	}
 }
 
-func (tw *TableWriter) RenderNDJSON() string {
-	var out strings.Builder
-	rows := tw.rawRows
+// columnHeaders returns the names of columns including ROWNUM,
+// the names are C1, C2... if the header is not set.
+func (tw *TableWriter) columnHeaders(rows []table.Row) []string {
 	headers := []string{}
 	if tw.rownum {
 		headers = append(headers, "ROWNUM")
@@ -438,19 +514,23 @@ func (tw *TableWriter) RenderNDJSON() string {
 			headers = append(headers, fmt.Sprintf("C%d", i+1))
 		}
 	}
+	return headers
+}
+
+func (tw *TableWriter) RenderNDJSON() string {
+	var out strings.Builder
+	rows := tw.rawRows
+	headers := tw.columnHeaders(rows)
+	kinds := tw.columnKinds()
 	for _, row := range rows {
 		out.WriteRune('{')
 		for i, col := range row {
 			if i > 0 {
 				out.WriteRune(',')
 			}
-			out.WriteString(fmt.Sprintf("\"%s\":", headers[i]))
-			switch v := col.(type) {
-			case string:
-				out.WriteString(fmt.Sprintf("\"%s\"", v))
-			default:
-				out.WriteString(fmt.Sprint(v))
-			}
+			writeJSONString(&out, headers[i])
+			out.WriteRune(':')
+			tw.writeJSONValue(&out, col, kindAt(kinds, i))
 		}
 		out.WriteRune('}')
 		out.WriteRune('\n')
@@ -462,7 +542,8 @@ func (tw *TableWriter) RenderNDJSON() string {
 	return ret
 }
 
-func renderRowsJSON(out *strings.Builder, rows []table.Row) {
+func (tw *TableWriter) renderRowsJSON(out *strings.Builder, rows []table.Row) {
+	kinds := tw.columnKinds()
 	for rIdx, row := range rows {
 		if rIdx > 0 {
 			out.WriteString(",")
@@ -472,71 +553,108 @@ func renderRowsJSON(out *strings.Builder, rows []table.Row) {
 			if i > 0 {
 				out.WriteRune(',')
 			}
-			switch v := col.(type) {
-			case string:
-				out.WriteString(fmt.Sprintf("\"%s\"", v))
-			default:
-				out.WriteString(fmt.Sprint(v))
-			}
+			tw.writeJSONValue(out, col, kindAt(kinds, i))
 		}
 		out.WriteString("]")
 	}
 }
 
+// RenderJSON writes the rows as a JSON document of "columns", "types" and "rows".
+// The first render writes the start of the document, the next renders continue the "rows" array
+// and the end is written by Close(), so that the rows of all pages make a single document.
 func (tw *TableWriter) RenderJSON() string {
 	var out strings.Builder
 	rows := tw.rawRows
 
-	if tw.renderCount > 0 {
-		renderRowsJSON(&out, rows)
-		return out.String()
+	if tw.renderCount == 0 {
+		headers := tw.columnHeaders(rows)
+		types := tw.columnKinds()
+		out.WriteString("{")
+		out.WriteString("\"columns\":[")
+		for i, h := range headers {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			writeJSONString(&out, h)
+		}
+		out.WriteString("],")
+		if len(types) == len(headers) {
+			out.WriteString("\"types\":[")
+			for i, ct := range types {
+				if i > 0 {
+					out.WriteString(",")
+				}
+				writeJSONString(&out, ct)
+			}
+			out.WriteString("],")
+		}
+		out.WriteString("\"rows\":[")
+	}
+	if tw.jsonRows > 0 && len(rows) > 0 {
+		// separate the rows of the previous page
+		out.WriteString(",")
+	}
+	tw.renderRowsJSON(&out, rows)
+	tw.jsonRows += len(rows)
+	ret := out.String()
+	if tw.output != nil {
+		tw.output.Write([]byte(ret))
+	}
+	return ret
+}
+
+// closeJSON writes the end of the document.
+func (tw *TableWriter) closeJSON() string {
+	ret := "]}\n"
+	if tw.output != nil {
+		tw.output.Write([]byte(ret))
 	}
+	return ret
+}
 
-	headers := []string{}
+// columnKinds returns the types of columns including ROWNUM.
+func (tw *TableWriter) columnKinds() []string {
 	types := []string{}
-
 	if tw.rownum {
-		headers = append(headers, "ROWNUM")
 		types = append(types, "long")
 	}
-	if len(tw.headerRow) > 0 {
-		for _, h := range tw.headerRow {
-			headers = append(headers, fmt.Sprint(h))
-		}
-	} else if len(rows) > 0 {
-		for i := 0; i < len(rows[0]); i++ {
-			headers = append(headers, fmt.Sprintf("C%d", i+1))
-		}
+	return append(types, tw.columnTypes...)
+}
+
+// kindAt returns the type of the i-th column, empty if it is unknown.
+func kindAt(kinds []string, i int) string {
+	if i < len(kinds) {
+		return kinds[i]
 	}
-	for _, colType := range tw.columnTypes {
-		types = append(types, colType)
+	return ""
+}
+
+// RenderParquet writes the rows as a row group of parquet to the output,
+// the parquet footer is written by Close().
+func (tw *TableWriter) RenderParquet() string {
+	if tw.err != nil {
+		return ""
 	}
-	out.WriteString("{")
-	out.WriteString("\"columns\":[")
-	for i, h := range headers {
-		if i > 0 {
-			out.WriteString(",")
+	if tw.parquet == nil {
+		if tw.output == nil {
+			tw.err = fmt.Errorf("parquet format requires an output")
+			return ""
 		}
-		out.WriteString(fmt.Sprintf("\"%s\"", h))
-	}
-	out.WriteString("],")
-	if len(types) == len(headers) {
-		out.WriteString("\"types\":[")
-		for i, ct := range types {
-			if i > 0 {
-				out.WriteString(",")
+		headers := tw.columnHeaders(tw.rawRows)
+		kinds := tw.columnKinds()
+		if len(kinds) != len(headers) {
+			// column types are unknown
+			kinds = make([]string, len(headers))
+			if tw.rownum {
+				kinds[0] = "long"
 			}
-			out.WriteString(fmt.Sprintf("\"%s\"", ct))
 		}
-		out.WriteString("],")
+		tw.parquet = newParquetWriter(tw.output, headers, kinds)
 	}
-	out.WriteString("\"rows\":[")
-	renderRowsJSON(&out, rows)
-	out.WriteString("]")
-	out.WriteString("}\n")
-	ret := out.String()
-	if tw.output != nil {
-		tw.output.Write([]byte(ret))
+	if len(tw.rawRows) > 0 {
+		if err := tw.parquet.WriteRowGroup(tw.rawRows); err != nil {
+			tw.err = err
+		}
 	}
-	return ret
+	return ""
 }
@@ -1,6 +1,18 @@
 package pretty
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of testdata")
 
 func TestTable(t *testing.T) {
 	tests := []TestCase{
@@ -307,3 +319,67 @@ func TestMakeRow(t *testing.T) {
 		RunTest(t, tc)
 	}
 }
+
+// TestTableJSONPages renders 5000 rows in the pages of 1000 rows,
+// the pages should make a single JSON document.
+func TestTableJSONPages(t *testing.T) {
+	var buf bytes.Buffer
+	tbl, err := Table(TableOption{Format: "json", Rownum: true, Header: true, Timeformat: "ms", Precision: -1, NullValue: "NULL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tbl.(*TableWriter)
+	tw.SetOutput(map[string]any{"writer": &buf})
+	tw.AppendHeader(table.Row{"NAME", "VALUE"})
+	tw.SetColumnTypes([]string{"string", "double"})
+	pages := 0
+	for i := 0; i < 5000; i++ {
+		var value any = float64(i) + 0.5
+		if i%1000 == 999 {
+			value = nil
+		}
+		tw.Append([]any{fmt.Sprintf("tag-%d", i%10), value})
+		if tw.RequirePageRender() {
+			tw.Render()
+			tw.PauseAndWait()
+			pages++
+		}
+	}
+	if _, err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if pages != 5 {
+		t.Fatalf("expected 5 pages, got %d", pages)
+	}
+
+	doc := struct {
+		Columns []string `json:"columns"`
+		Types   []string `json:"types"`
+		Rows    [][]any  `json:"rows"`
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if len(doc.Columns) != 3 || len(doc.Types) != 3 || len(doc.Rows) != 5000 {
+		t.Fatalf("expected 3 columns and 5000 rows, got %d columns, %d rows", len(doc.Columns), len(doc.Rows))
+	}
+	for i, row := range doc.Rows {
+		if row[0] != float64(i+1) {
+			t.Fatalf("row %d: unexpected ROWNUM %v", i, row[0])
+		}
+	}
+
+	golden := filepath.Join("testdata", "json_pages.golden")
+	if *updateGolden {
+		if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), expect) {
+		t.Errorf("output is different from %s, run with -update if it is intended", golden)
+	}
+}
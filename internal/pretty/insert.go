@@ -0,0 +1,134 @@
+package pretty
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SetPreamble sets the statements that are written before the INSERT statements,
+// e.g. CREATE TABLE of the table.
+func (tw *TableWriter) SetPreamble(preamble string) {
+	tw.preamble = preamble
+}
+
+// RenderInsert writes the rows as INSERT statements of the table,
+// the values are SQL literals of the column types and ROWNUM is not inserted.
+func (tw *TableWriter) RenderInsert() string {
+	if tw.table == "" {
+		if tw.err == nil {
+			tw.err = fmt.Errorf("insert format requires a table name")
+		}
+		return ""
+	}
+	if tw.renderCount == 0 {
+		// the server has no literal of binary, a quoted string is stored as its characters
+		for i, kind := range tw.columnTypes {
+			if kind == "binary" {
+				tw.err = fmt.Errorf("insert format can not write the binary column %s", tw.columnName(i))
+				return ""
+			}
+		}
+	}
+	if tw.err != nil {
+		return ""
+	}
+	var out strings.Builder
+	if tw.renderCount == 0 && tw.preamble != "" {
+		out.WriteString(strings.TrimRight(strings.TrimSpace(tw.preamble), ";"))
+		out.WriteString(";\n")
+	}
+	skip := 0
+	if tw.rownum {
+		skip = 1
+	}
+	prefix := "INSERT INTO " + tw.table
+	if len(tw.headerRow) > 0 {
+		names := make([]string, len(tw.headerRow))
+		for i, h := range tw.headerRow {
+			names[i] = fmt.Sprint(h)
+		}
+		prefix += "(" + strings.Join(names, ",") + ")"
+	}
+	prefix += " VALUES("
+	for _, row := range tw.rawRows {
+		if len(row) < skip {
+			continue
+		}
+		out.WriteString(prefix)
+		for i, col := range row[skip:] {
+			if i > 0 {
+				out.WriteRune(',')
+			}
+			if _, ok := col.([]byte); ok {
+				tw.err = fmt.Errorf("insert format can not write the binary column %s", tw.columnName(i))
+				return ""
+			}
+			tw.writeSQLLiteral(&out, col)
+		}
+		out.WriteString(");\n")
+	}
+	ret := out.String()
+	if tw.output != nil {
+		tw.output.Write([]byte(ret))
+	}
+	return ret
+}
+
+// columnName returns the name of the i-th column except ROWNUM.
+func (tw *TableWriter) columnName(i int) string {
+	if i < len(tw.headerRow) {
+		return fmt.Sprint(tw.headerRow[i])
+	}
+	return fmt.Sprintf("C%d", i+1)
+}
+
+// writeSQLLiteral writes the value as a SQL literal, nil is NULL
+// and the datetime is the nanoseconds since the epoch, so that it does not depend
+// on the time zone of the session that replays it.
+func (tw *TableWriter) writeSQLLiteral(out *strings.Builder, v any) {
+	switch val := v.(type) {
+	case nil:
+		out.WriteString("NULL")
+	case string:
+		writeSQLString(out, val)
+	case time.Time:
+		out.WriteString(strconv.FormatInt(val.UnixNano(), 10))
+	case *time.Time:
+		tw.writeSQLLiteral(out, *val)
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			out.WriteString("NULL")
+			return
+		}
+		out.WriteString(strconv.FormatFloat(val, 'g', -1, 64))
+	case float32:
+		if math.IsNaN(float64(val)) || math.IsInf(float64(val), 0) {
+			out.WriteString("NULL")
+			return
+		}
+		out.WriteString(strconv.FormatFloat(float64(val), 'g', -1, 32))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		fmt.Fprint(out, val)
+	case bool:
+		if val {
+			out.WriteString("1")
+		} else {
+			out.WriteString("0")
+		}
+	case net.IP:
+		writeSQLString(out, val.String())
+	default:
+		writeSQLString(out, fmt.Sprint(val))
+	}
+}
+
+// writeSQLString writes the string quoted by single quotes, the single quotes in it are doubled.
+func writeSQLString(out *strings.Builder, s string) {
+	out.WriteByte('\'')
+	out.WriteString(strings.ReplaceAll(s, "'", "''"))
+	out.WriteByte('\'')
+}
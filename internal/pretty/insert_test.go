@@ -0,0 +1,66 @@
+package pretty
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+func TestTableInsert(t *testing.T) {
+	var buf bytes.Buffer
+	tbl, err := Table(TableOption{Format: "insert", Table: "TARGET", Tz: "Asia/Seoul", Rownum: true, Header: true, Precision: -1, NullValue: "NULL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tbl.(*TableWriter)
+	tw.SetOutput(map[string]any{"writer": &buf})
+	tw.SetPreamble("CREATE TABLE TARGET (NAME varchar(40), TIME datetime, VALUE double)")
+	tw.AppendHeader(table.Row{"NAME", "TIME", "VALUE"})
+	tw.SetColumnTypes([]string{"string", "datetime", "double"})
+
+	ts := time.Date(2025, 1, 1, 15, 0, 0, 123456789, time.UTC)
+	tw.Append([]any{"it's", ts, 1.5})
+	tw.Render()
+	tw.PauseAndWait()
+	tw.Append([]any{nil, ts, math.NaN()})
+	if _, err := tw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// the datetime is the nanoseconds since the epoch regardless of --tz
+	expect := []string{
+		"CREATE TABLE TARGET (NAME varchar(40), TIME datetime, VALUE double);",
+		"INSERT INTO TARGET(NAME,TIME,VALUE) VALUES('it''s',1735743600123456789,1.5);",
+		"INSERT INTO TARGET(NAME,TIME,VALUE) VALUES(NULL,1735743600123456789,NULL);",
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(expect) {
+		t.Fatalf("expected %d lines, got %d\n%s", len(expect), len(lines), buf.String())
+	}
+	for i := range expect {
+		if lines[i] != expect[i] {
+			t.Errorf("line %d\nexpected %s\n     got %s", i, expect[i], lines[i])
+		}
+	}
+
+	// the server has no literal of binary
+	tbl, _ = Table(TableOption{Format: "insert", Table: "TARGET", Header: true})
+	tw = tbl.(*TableWriter)
+	tw.AppendHeader(table.Row{"NAME", "DATA"})
+	tw.SetColumnTypes([]string{"string", "binary"})
+	tw.Append([]any{"a", []byte{0x00, 0xff}})
+	if _, err := tw.Close(); err == nil || err.Error() != "insert format can not write the binary column DATA" {
+		t.Errorf("expected binary column error, got %v", err)
+	}
+
+	tbl, _ = Table(TableOption{Format: "insert"})
+	tw = tbl.(*TableWriter)
+	tw.Append([]any{1})
+	if _, err := tw.Close(); err == nil || err.Error() != "insert format requires a table name" {
+		t.Errorf("expected table name error, got %v", err)
+	}
+}
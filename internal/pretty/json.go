@@ -0,0 +1,91 @@
+package pretty
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// writeJSONValue writes the value of the column kind as JSON,
+// nil is null, the values of the json columns are embedded as they are.
+func (tw *TableWriter) writeJSONValue(out *strings.Builder, v any, kind string) {
+	switch val := v.(type) {
+	case nil:
+		out.WriteString("null")
+	case string:
+		if kind == "json" {
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, []byte(val)); err == nil {
+				out.Write(buf.Bytes())
+				return
+			}
+		}
+		writeJSONString(out, val)
+	case []byte:
+		if tw.blobEncoding == "HEX" {
+			writeJSONString(out, hex.EncodeToString(val))
+		} else {
+			writeJSONString(out, base64.StdEncoding.EncodeToString(val))
+		}
+	case time.Time:
+		switch tv := tw.formatTime(val).(type) {
+		case string:
+			writeJSONString(out, tv)
+		default:
+			fmt.Fprint(out, tv)
+		}
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			out.WriteString("null")
+			return
+		}
+		b, _ := json.Marshal(val)
+		out.Write(b)
+	case float32:
+		if math.IsNaN(float64(val)) || math.IsInf(float64(val), 0) {
+			out.WriteString("null")
+			return
+		}
+		b, _ := json.Marshal(val)
+		out.Write(b)
+	default:
+		if b, err := json.Marshal(val); err == nil {
+			out.Write(b)
+		} else {
+			writeJSONString(out, fmt.Sprint(val))
+		}
+	}
+}
+
+// writeJSONString writes the string as a JSON string,
+// the invalid UTF-8 bytes are replaced with U+FFFD.
+func writeJSONString(out *strings.Builder, s string) {
+	out.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		switch {
+		case r == '"':
+			out.WriteString(`\"`)
+		case r == '\\':
+			out.WriteString(`\\`)
+		case r == '\n':
+			out.WriteString(`\n`)
+		case r == '\r':
+			out.WriteString(`\r`)
+		case r == '\t':
+			out.WriteString(`\t`)
+		case r < 0x20, r == '\u2028', r == '\u2029':
+			fmt.Fprintf(out, `\u%04x`, r)
+		default:
+			out.WriteRune(r)
+		}
+	}
+	out.WriteByte('"')
+}
@@ -0,0 +1,80 @@
+package pretty
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+func TestTableJSONEncoding(t *testing.T) {
+	ts := time.Unix(1700000000, 123000000)
+	row := func() []any {
+		return []any{"say \"hi\"\\\n\tbye\u2028", ts, math.NaN(), []byte{0x00, 0xff}, nil, `{ "a": [1, 2] }`, "{not json", math.Inf(1), float32(1.5)}
+	}
+	header := table.Row{"TEXT", "TIME", "VALUE", "DATA", "EMPTY", "DOC", "BAD", "INF", "F32"}
+	types := []string{"string", "datetime", "double", "binary", "string", "json", "json", "double", "float"}
+
+	tests := []struct {
+		name   string
+		opt    TableOption
+		expect []string
+	}{
+		{
+			name: "ndjson",
+			opt:  TableOption{Format: "ndjson", Timeformat: "ms", Precision: -1, NullValue: "NULL"},
+			expect: []string{
+				`{"TEXT":"say \"hi\"\\\n\tbye\u2028","TIME":1700000000123,"VALUE":null,"DATA":"AP8=","EMPTY":null,"DOC":{"a":[1,2]},"BAD":"{not json","INF":null,"F32":1.5}`,
+			},
+		},
+		{
+			name: "ndjson_hex_rfc3339",
+			opt:  TableOption{Format: "ndjson", Timeformat: "rfc3339", Tz: "UTC", Precision: -1, BlobEncoding: "hex", Rownum: true},
+			expect: []string{
+				`{"ROWNUM":1,"TEXT":"say \"hi\"\\\n\tbye\u2028","TIME":"2023-11-14T22:13:20.123Z","VALUE":null,"DATA":"00ff","EMPTY":null,"DOC":{"a":[1,2]},"BAD":"{not json","INF":null,"F32":1.5}`,
+			},
+		},
+		{
+			name: "json",
+			opt:  TableOption{Format: "json", Timeformat: "ns", Precision: -1},
+			expect: []string{
+				`{"columns":["TEXT","TIME","VALUE","DATA","EMPTY","DOC","BAD","INF","F32"],` +
+					`"types":["string","datetime","double","binary","string","json","json","double","float"],` +
+					`"rows":[["say \"hi\"\\\n\tbye\u2028",1700000000123000000,null,"AP8=",null,{"a":[1,2]},"{not json",null,1.5]]}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tbl, err := Table(tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tw := tbl.(*TableWriter)
+			tw.SetOutput(map[string]any{"writer": &buf})
+			tw.AppendHeader(header)
+			tw.SetColumnTypes(types)
+			tw.Append(row())
+			if _, err := tw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if len(lines) != len(tt.expect) {
+				t.Fatalf("expected %d lines, got %d\n%s", len(tt.expect), len(lines), buf.String())
+			}
+			for i, line := range lines {
+				if line != tt.expect[i] {
+					t.Errorf("line %d:\nexpected %s\n     got %s", i, tt.expect[i], line)
+				}
+				if !json.Valid([]byte(line)) {
+					t.Errorf("line %d is not a valid json: %s", i, line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package pretty
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress/snappy"
+)
+
+// rows of a row group in parquet format
+const parquetRowGroupSize = 10000
+
+// parquetWriter writes the rows of each page as a row group,
+// so that the memory usage is bounded regardless of the number of rows.
+type parquetWriter struct {
+	w     *parquet.Writer
+	kinds []string // column types of the schema
+}
+
+// orderedGroup is a parquet.Group that keeps the fields in the order of the columns,
+// parquet.Group sorts the fields by name.
+type orderedGroup struct {
+	parquet.Group
+	names []string
+}
+
+func (g orderedGroup) Fields() []parquet.Field {
+	fields := g.Group.Fields()
+	slices.SortFunc(fields, func(a, b parquet.Field) int {
+		return slices.Index(g.names, a.Name()) - slices.Index(g.names, b.Name())
+	})
+	return fields
+}
+
+func parquetNode(kind string) parquet.Node {
+	switch kind {
+	case "int8":
+		return parquet.Int(8)
+	case "int16":
+		return parquet.Int(16)
+	case "int32":
+		return parquet.Int(32)
+	case "int64", "long":
+		return parquet.Int(64)
+	case "uint16":
+		return parquet.Uint(16)
+	case "uint32":
+		return parquet.Uint(32)
+	case "uint64":
+		return parquet.Uint(64)
+	case "float":
+		return parquet.Leaf(parquet.FloatType)
+	case "double":
+		return parquet.Leaf(parquet.DoubleType)
+	case "bool":
+		return parquet.Leaf(parquet.BooleanType)
+	case "datetime":
+		return parquet.Timestamp(parquet.Nanosecond)
+	case "binary":
+		return parquet.Leaf(parquet.ByteArrayType)
+	default:
+		// string, ipv4, ipv6 and the others
+		return parquet.String()
+	}
+}
+
+func newParquetWriter(out io.Writer, headers []string, kinds []string) *parquetWriter {
+	group := parquet.Group{}
+	names := make([]string, len(headers))
+	for i, h := range headers {
+		// parquet requires unique column names
+		name := h
+		for n := 2; slices.Contains(names[:i], name); n++ {
+			name = fmt.Sprintf("%s_%d", h, n)
+		}
+		names[i] = name
+		group[name] = parquet.Optional(parquetNode(kinds[i]))
+	}
+	schema := parquet.NewSchema("machbase", orderedGroup{Group: group, names: names})
+	return &parquetWriter{
+		w:     parquet.NewWriter(out, schema, parquet.Compression(&snappy.Codec{})),
+		kinds: kinds,
+	}
+}
+
+// WriteRowGroup writes the rows as a row group.
+func (pw *parquetWriter) WriteRowGroup(rows []table.Row) error {
+	buf := make([]parquet.Row, 0, len(rows))
+	for _, row := range rows {
+		pr := make(parquet.Row, len(pw.kinds))
+		for i, kind := range pw.kinds {
+			var v any
+			if i < len(row) {
+				v = row[i]
+			}
+			pv, err := parquetValue(kind, v)
+			if err != nil {
+				return err
+			}
+			if pv.IsNull() {
+				pr[i] = pv.Level(0, 0, i)
+			} else {
+				pr[i] = pv.Level(0, 1, i)
+			}
+		}
+		buf = append(buf, pr)
+	}
+	if _, err := pw.w.WriteRows(buf); err != nil {
+		return err
+	}
+	return pw.w.Flush()
+}
+
+func (pw *parquetWriter) Close() error {
+	return pw.w.Close()
+}
+
+func parquetValue(kind string, v any) (parquet.Value, error) {
+	if v == nil {
+		return parquet.NullValue(), nil
+	}
+	switch kind {
+	case "int8", "int16", "int32":
+		n, err := toInt64(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return parquet.Value{}, fmt.Errorf("parquet: value %d is out of range of %s", n, kind)
+		}
+		return parquet.Int32Value(int32(n)), nil
+	case "int64", "long":
+		n, err := toInt64(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.Int64Value(n), nil
+	case "uint16", "uint32":
+		n, err := toUint64(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		if n > math.MaxUint32 {
+			return parquet.Value{}, fmt.Errorf("parquet: value %d is out of range of %s", n, kind)
+		}
+		// the unsigned integers are stored in the bits of the physical type
+		return parquet.Int32Value(int32(uint32(n))), nil
+	case "uint64":
+		n, err := toUint64(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.Int64Value(int64(n)), nil
+	case "float":
+		f, err := toFloat64(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.FloatValue(float32(f)), nil
+	case "double":
+		f, err := toFloat64(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.DoubleValue(f), nil
+	case "bool":
+		if b, ok := v.(bool); ok {
+			return parquet.BooleanValue(b), nil
+		}
+		return parquet.Value{}, fmt.Errorf("parquet: invalid bool value %v", v)
+	case "datetime":
+		switch t := v.(type) {
+		case time.Time:
+			return parquet.Int64Value(t.UnixNano()), nil
+		case *time.Time:
+			return parquet.Int64Value(t.UnixNano()), nil
+		default:
+			n, err := toInt64(v)
+			if err != nil {
+				return parquet.Value{}, err
+			}
+			return parquet.Int64Value(n), nil
+		}
+	case "binary":
+		if b, ok := v.([]byte); ok {
+			return parquet.ByteArrayValue(b), nil
+		}
+		return parquet.ByteArrayValue([]byte(fmt.Sprint(v))), nil
+	default:
+		switch s := v.(type) {
+		case string:
+			return parquet.ByteArrayValue([]byte(s)), nil
+		case net.IP:
+			return parquet.ByteArrayValue([]byte(s.String())), nil
+		case time.Time:
+			return parquet.ByteArrayValue([]byte(s.Format(time.RFC3339Nano))), nil
+		default:
+			return parquet.ByteArrayValue([]byte(fmt.Sprint(v))), nil
+		}
+	}
+}
+
+func toInt64(v any) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int8:
+		return int64(n), nil
+	case int16:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint:
+		return toInt64(uint64(n))
+	case uint8:
+		return int64(n), nil
+	case uint16:
+		return int64(n), nil
+	case uint32:
+		return int64(n), nil
+	case uint64:
+		if n > math.MaxInt64 {
+			return 0, fmt.Errorf("integer value %d is out of range of int64", n)
+		}
+		return int64(n), nil
+	case float32:
+		return int64(n), nil
+	case float64:
+		return int64(n), nil
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	default:
+		return 0, fmt.Errorf("invalid integer value %v (%T)", v, v)
+	}
+}
+
+func toUint64(v any) (uint64, error) {
+	switch n := v.(type) {
+	case uint:
+		return uint64(n), nil
+	case uint8:
+		return uint64(n), nil
+	case uint16:
+		return uint64(n), nil
+	case uint32:
+		return uint64(n), nil
+	case uint64:
+		return n, nil
+	case string:
+		return strconv.ParseUint(n, 10, 64)
+	default:
+		i, err := toInt64(v)
+		if err != nil {
+			return 0, err
+		}
+		if i < 0 {
+			return 0, fmt.Errorf("integer value %d is out of range of uint64", i)
+		}
+		return uint64(i), nil
+	}
+}
+
+func toFloat64(v any) (float64, error) {
+	switch n := v.(type) {
+	case float32:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	case string:
+		return strconv.ParseFloat(n, 64)
+	default:
+		i, err := toInt64(v)
+		if err != nil {
+			return math.NaN(), fmt.Errorf("invalid float value %v (%T)", v, v)
+		}
+		return float64(i), nil
+	}
+}
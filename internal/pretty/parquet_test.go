@@ -0,0 +1,148 @@
+package pretty
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestTableParquet(t *testing.T) {
+	var buf bytes.Buffer
+	tbl, err := Table(TableOption{Format: "parquet", Rownum: true, Header: true, NullValue: "NULL", Precision: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tbl.(*TableWriter)
+	tw.SetOutput(map[string]any{"writer": &buf})
+	tw.AppendHeader(table.Row{"NAME", "TIME", "VALUE", "NAME"})
+	tw.SetColumnTypes([]string{"string", "datetime", "double", "string"})
+
+	ts := time.Unix(1700000000, 123456789)
+	const nRows = parquetRowGroupSize*2 + 5
+	for i := 0; i < nRows; i++ {
+		var value any = float64(i) + 0.5
+		if i == 1 {
+			value = nil
+		}
+		tw.Append([]any{"tag", ts.Add(time.Duration(i) * time.Second), value, "dup"})
+		if tw.RequirePageRender() {
+			tw.Render()
+			tw.PauseAndWait()
+		}
+	}
+	if _, err := tw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("invalid parquet file: %v", err)
+	}
+	if n := f.NumRows(); n != nRows {
+		t.Fatalf("expected %d rows, got %d", nRows, n)
+	}
+	if n := len(f.RowGroups()); n != 3 {
+		t.Errorf("expected 3 row groups, got %d", n)
+	}
+	fields := f.Schema().Fields()
+	names := []string{}
+	for _, fld := range fields {
+		names = append(names, fld.Name())
+	}
+	if want := []string{"ROWNUM", "NAME", "TIME", "VALUE", "NAME_2"}; !slices.Equal(names, want) {
+		t.Errorf("expected columns %v, got %v", want, names)
+	}
+	if lt := fields[2].Type().LogicalType(); lt == nil || !strings.HasPrefix(lt.String(), "TIMESTAMP") {
+		t.Errorf("TIME should be a timestamp, got %v", fields[2].Type())
+	}
+	if k := fields[3].Type().Kind(); k != parquet.Double {
+		t.Errorf("VALUE should be double, got %v", k)
+	}
+
+	rows := make([]parquet.Row, 2)
+	r := parquet.NewReader(f)
+	defer r.Close()
+	if n, _ := r.ReadRows(rows); n != 2 {
+		t.Fatalf("failed to read rows, %d", n)
+	}
+	if v := rows[0][2].Int64(); v != ts.UnixNano() {
+		t.Errorf("expected time %d, got %d", ts.UnixNano(), v)
+	}
+	if v := rows[0][3].Double(); v != 0.5 {
+		t.Errorf("expected value 0.5, got %v", v)
+	}
+	if !rows[1][3].IsNull() {
+		t.Errorf("expected null, got %v", rows[1][3])
+	}
+}
+
+func TestTableParquetUnsigned(t *testing.T) {
+	write := func(types []string, values ...[]any) ([]byte, error) {
+		var buf bytes.Buffer
+		tbl, err := Table(TableOption{Format: "parquet", Header: true, NullValue: "NULL", Precision: -1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tw := tbl.(*TableWriter)
+		tw.SetOutput(map[string]any{"writer": &buf})
+		headers := table.Row{}
+		for i := range types {
+			headers = append(headers, fmt.Sprintf("C%d", i))
+		}
+		tw.AppendHeader(headers)
+		tw.SetColumnTypes(types)
+		for _, row := range values {
+			tw.Append(row)
+		}
+		_, err = tw.Close()
+		return buf.Bytes(), err
+	}
+
+	data, err := write([]string{"uint16", "uint32", "uint64"},
+		[]any{uint16(math.MaxUint16), uint32(math.MaxUint32), uint64(math.MaxUint64)})
+	if err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	f, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("invalid parquet file: %v", err)
+	}
+	for i, want := range []string{"INT(16,false)", "INT(32,false)", "INT(64,false)"} {
+		if lt := f.Schema().Fields()[i].Type().LogicalType(); lt == nil || lt.String() != want {
+			t.Errorf("column %d should be %s, got %v", i, want, lt)
+		}
+	}
+	rows := make([]parquet.Row, 1)
+	r := parquet.NewReader(f)
+	defer r.Close()
+	if n, _ := r.ReadRows(rows); n != 1 {
+		t.Fatalf("failed to read rows, %d", n)
+	}
+	if v := rows[0][0].Uint32(); v != math.MaxUint16 {
+		t.Errorf("expected %d, got %d", math.MaxUint16, v)
+	}
+	if v := rows[0][1].Uint32(); v != math.MaxUint32 {
+		t.Errorf("expected %d, got %d", uint32(math.MaxUint32), v)
+	}
+	if v := rows[0][2].Uint64(); v != math.MaxUint64 {
+		t.Errorf("expected %d, got %d", uint64(math.MaxUint64), v)
+	}
+
+	// no unsigned type to keep the value, it is an error instead of a negative number
+	if _, err := write([]string{"int64"}, []any{uint64(math.MaxInt64) + 1}); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("expected out of range error, got %v", err)
+	}
+	if _, err := write([]string{"int32"}, []any{int64(math.MaxInt32) + 1}); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("expected out of range error, got %v", err)
+	}
+	if _, err := write([]string{"uint32"}, []any{int64(-1)}); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("expected out of range error, got %v", err)
+	}
+}
@@ -25,6 +25,7 @@ func Module(rt *goja.Runtime, module *goja.Object) {
 	exports.Set("Durations", Durations)
 	// time parsing helper
 	exports.Set("parseTime", parseTime)
+	exports.Set("splitDays", splitDays)
 	// terminal helpers
 	exports.Set("isTerminal", IsTerminal)
 	exports.Set("getTerminalSize", GetTerminalSize)
@@ -90,6 +91,41 @@ LOAD_LOC_DONE:
 	}
 }
 
+// Day is a day of a time range.
+type Day struct {
+	Name string    // date of the day, 2006-01-02
+	From time.Time // start of the day or the range
+	To   time.Time // end of the day or the range, exclusive
+}
+
+// splitDays splits the time range [from, to) into the days in the time zone.
+func splitDays(from time.Time, to time.Time, tz string) ([]Day, error) {
+	loc := time.Local
+	switch strings.ToLower(tz) {
+	case "", "local":
+	case "utc":
+		loc = time.UTC
+	default:
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load location '%s': %v", tz, err)
+		}
+		loc = l
+	}
+	ret := []Day{}
+	from = from.In(loc)
+	for from.Before(to) {
+		y, m, d := from.Date()
+		next := time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+		if next.After(to) {
+			next = to
+		}
+		ret = append(ret, Day{Name: from.Format("2006-01-02"), From: from, To: next})
+		from = next.In(loc)
+	}
+	return ret, nil
+}
+
 func IsTerminal() bool {
 	return term.IsTerminal(int(os.Stdin.Fd()))
 }
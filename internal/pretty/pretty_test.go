@@ -212,3 +212,53 @@ func TestDurations(t *testing.T) {
 		RunTest(t, tc)
 	}
 }
+
+func TestSplitDays(t *testing.T) {
+	tests := []TestCase{
+		{
+			name: "splitDays_utc",
+			script: `
+				const pretty = require('/usr/lib/pretty');
+				const from = pretty.parseTime('2025-01-01T12:00:00Z', '', 'UTC');
+				const to = pretty.parseTime('2025-01-03T06:00:00Z', '', 'UTC');
+				for (const d of pretty.splitDays(from, to, 'UTC')) {
+					console.println(d.name, d.from.unix(), d.to.unix());
+				}
+			`,
+			output: []string{
+				"2025-01-01 1735732800 1735776000",
+				"2025-01-02 1735776000 1735862400",
+				"2025-01-03 1735862400 1735884000",
+			},
+		},
+		{
+			name: "splitDays_tz",
+			script: `
+				const pretty = require('/usr/lib/pretty');
+				const from = pretty.parseTime('2025-01-01T00:00:00Z', '', 'UTC');
+				const to = pretty.parseTime('2025-01-02T00:00:00Z', '', 'UTC');
+				for (const d of pretty.splitDays(from, to, 'Asia/Seoul')) {
+					console.println(d.name, d.from.unix(), d.to.unix());
+				}
+			`,
+			output: []string{
+				"2025-01-01 1735689600 1735743600",
+				"2025-01-02 1735743600 1735776000",
+			},
+		},
+		{
+			name: "splitDays_empty",
+			script: `
+				const pretty = require('/usr/lib/pretty');
+				const t = pretty.parseTime('2025-01-01T00:00:00Z', '', 'UTC');
+				console.println(pretty.splitDays(t, t, 'UTC').length);
+			`,
+			output: []string{
+				"0",
+			},
+		},
+	}
+	for _, tc := range tests {
+		RunTest(t, tc)
+	}
+}
@@ -0,0 +1,303 @@
+package pretty
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// cell styles of xl/styles.xml
+const (
+	xlsxStyleDefault  = 0
+	xlsxStyleDatetime = 1
+	xlsxStyleHeader   = 2
+)
+
+// maximum width of a column in characters
+const xlsxMaxWidth = 80
+
+// maximum number of rows of a sheet, including the header row
+const xlsxMaxRows = 1048576
+
+// xlsxWriter writes the rows of a sheet into a temporary file,
+// the file is copied into the xlsx package by Close() after the widths of columns are known.
+type xlsxWriter struct {
+	out    io.Writer
+	tz     *time.Location
+	blob   string
+	header bool
+	widths []int
+	tmp    *os.File
+	w      *bufio.Writer
+	rowNum int
+}
+
+func newXLSXWriter(out io.Writer, tz *time.Location, blobEncoding string, headers []string, header bool) (*xlsxWriter, error) {
+	tmp, err := os.CreateTemp("", "neo-shell-*.xlsx.tmp")
+	if err != nil {
+		return nil, err
+	}
+	xw := &xlsxWriter{
+		out:    out,
+		tz:     tz,
+		blob:   blobEncoding,
+		header: header,
+		widths: make([]int, len(headers)),
+		tmp:    tmp,
+		w:      bufio.NewWriter(tmp),
+	}
+	if header {
+		row := make(table.Row, len(headers))
+		for i, h := range headers {
+			row[i] = h
+		}
+		xw.writeRow(row, xlsxStyleHeader)
+	}
+	return xw, nil
+}
+
+// WriteRows writes the rows to the temporary file,
+// it returns an error if the rows exceed the limit of a sheet.
+func (xw *xlsxWriter) WriteRows(rows []table.Row) error {
+	for _, row := range rows {
+		if err := xw.writeRow(row, xlsxStyleDefault); err != nil {
+			return err
+		}
+	}
+	return xw.w.Flush()
+}
+
+func (xw *xlsxWriter) writeRow(row table.Row, style int) error {
+	if xw.rowNum >= xlsxMaxRows {
+		return fmt.Errorf("xlsx sheet is limited to %d rows", xlsxMaxRows)
+	}
+	xw.rowNum++
+	fmt.Fprintf(xw.w, `<row r="%d">`, xw.rowNum)
+	for i, v := range row {
+		if v == nil {
+			continue
+		}
+		ref := xlsxColumnName(i) + strconv.Itoa(xw.rowNum)
+		text, width := xw.writeCell(ref, v, style)
+		if i < len(xw.widths) {
+			if text != "" {
+				width = utf8.RuneCountInString(text)
+			}
+			xw.widths[i] = max(xw.widths[i], width)
+		}
+	}
+	xw.w.WriteString(`</row>`)
+	return nil
+}
+
+// writeCell writes the cell of the value, it returns the text of a string cell
+// or the width of a number cell.
+func (xw *xlsxWriter) writeCell(ref string, v any, style int) (string, int) {
+	switch val := v.(type) {
+	case time.Time:
+		fmt.Fprintf(xw.w, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxStyleDatetime, xlsxSerial(val.In(xw.tz)))
+		return "", len("2006-01-02 15:04:05.000")
+	case *time.Time:
+		return xw.writeCell(ref, *val, style)
+	case bool:
+		b := 0
+		if val {
+			b = 1
+		}
+		fmt.Fprintf(xw.w, `<c r="%s" t="b"><v>%d</v></c>`, ref, b)
+		return "", 5
+	case float32:
+		return xw.writeNumber(ref, float64(val), strconv.FormatFloat(float64(val), 'g', -1, 32))
+	case float64:
+		return xw.writeNumber(ref, val, strconv.FormatFloat(val, 'g', -1, 64))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		s := fmt.Sprint(val)
+		fmt.Fprintf(xw.w, `<c r="%s"><v>%s</v></c>`, ref, s)
+		return "", len(s)
+	case []byte:
+		if xw.blob == "HEX" {
+			return xw.writeString(ref, hex.EncodeToString(val), style)
+		}
+		return xw.writeString(ref, base64.StdEncoding.EncodeToString(val), style)
+	case net.IP:
+		return xw.writeString(ref, val.String(), style)
+	case string:
+		return xw.writeString(ref, val, style)
+	default:
+		return xw.writeString(ref, fmt.Sprint(val), style)
+	}
+}
+
+func (xw *xlsxWriter) writeNumber(ref string, f float64, s string) (string, int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		// not a number in a spreadsheet
+		return xw.writeString(ref, s, xlsxStyleDefault)
+	}
+	fmt.Fprintf(xw.w, `<c r="%s"><v>%s</v></c>`, ref, s)
+	return "", len(s)
+}
+
+func (xw *xlsxWriter) writeString(ref string, s string, style int) (string, int) {
+	fmt.Fprintf(xw.w, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">`, ref, style)
+	xml.EscapeText(xw.w, []byte(s))
+	xw.w.WriteString(`</t></is></c>`)
+	return s, 0
+}
+
+// Abort removes the temporary file without writing the xlsx package.
+func (xw *xlsxWriter) Abort() error {
+	xw.tmp.Close()
+	return os.Remove(xw.tmp.Name())
+}
+
+// Close writes the xlsx package to the output and removes the temporary file.
+func (xw *xlsxWriter) Close() error {
+	defer os.Remove(xw.tmp.Name())
+	defer xw.tmp.Close()
+	if err := xw.w.Flush(); err != nil {
+		return err
+	}
+	if _, err := xw.tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(xw.out)
+	for _, f := range []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	} {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, f.content); err != nil {
+			return err
+		}
+	}
+	w, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if xw.header {
+		// freeze the header row
+		bw.WriteString(`<sheetViews><sheetView workbookViewId="0">` +
+			`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>` +
+			`</sheetView></sheetViews>`)
+	}
+	if len(xw.widths) > 0 {
+		bw.WriteString(`<cols>`)
+		for i, width := range xw.widths {
+			fmt.Fprintf(bw, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, min(width, xlsxMaxWidth)+2)
+		}
+		bw.WriteString(`</cols>`)
+	}
+	bw.WriteString(`<sheetData>`)
+	if _, err := io.Copy(bw, xw.tmp); err != nil {
+		return err
+	}
+	bw.WriteString(`</sheetData></worksheet>`)
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// xlsxColumnName returns the name of the column of the index, A, B, ... Z, AA, AB...
+func xlsxColumnName(idx int) string {
+	name := ""
+	for idx >= 0 {
+		name = string(rune('A'+idx%26)) + name
+		idx = idx/26 - 1
+	}
+	return name
+}
+
+// xlsxSerial returns the serial number of the time in days since 1899-12-30,
+// by the wall clock of the time zone of the time.
+func xlsxSerial(t time.Time) string {
+	y, m, d := t.Date()
+	wall := time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	secs := float64(wall.Unix()-xlsxEpoch.Unix()) + float64(wall.Nanosecond())/1e9
+	return strconv.FormatFloat(secs/86400, 'f', -1, 64)
+}
+
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// RenderXLSX writes the rows to the sheet, the xlsx package is written to the output by Close().
+func (tw *TableWriter) RenderXLSX() string {
+	if tw.err != nil {
+		return ""
+	}
+	if tw.xlsx == nil {
+		if tw.output == nil {
+			tw.err = fmt.Errorf("xlsx format requires an output")
+			return ""
+		}
+		headers := tw.columnHeaders(tw.rawRows)
+		xw, err := newXLSXWriter(tw.output, tw.tz, tw.blobEncoding, headers, tw.header)
+		if err != nil {
+			tw.err = err
+			return ""
+		}
+		tw.xlsx = xw
+	}
+	if err := tw.xlsx.WriteRows(tw.rawRows); err != nil {
+		tw.err = err
+	}
+	return ""
+}
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const xlsxRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// the cellXfs are in the order of xlsxStyleDefault, xlsxStyleDatetime and xlsxStyleHeader
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss.000"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
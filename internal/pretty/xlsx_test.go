@@ -0,0 +1,163 @@
+package pretty
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+type xlsxSheet struct {
+	Panes []struct {
+		YSplit string `xml:"ySplit,attr"`
+		State  string `xml:"state,attr"`
+	} `xml:"sheetViews>sheetView>pane"`
+	Cols []struct {
+		Width string `xml:"width,attr"`
+	} `xml:"cols>col"`
+	Rows []struct {
+		Cells []struct {
+			Ref   string `xml:"r,attr"`
+			Type  string `xml:"t,attr"`
+			Style string `xml:"s,attr"`
+			Value string `xml:"v"`
+			Text  string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestTableXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	tbl, err := Table(TableOption{Format: "xlsx", Header: true, Tz: "Asia/Seoul", NullValue: "NULL", Precision: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tbl.(*TableWriter)
+	tw.SetOutput(map[string]any{"writer": &buf})
+	tw.AppendHeader(table.Row{"NAME", "TIME", "VALUE"})
+	tw.SetColumnTypes([]string{"string", "datetime", "double"})
+
+	ts := time.Date(2025, 1, 1, 15, 0, 0, 0, time.UTC) // 2025-01-02 00:00:00 in Asia/Seoul
+	const nRows = 2500
+	for i := 0; i < nRows; i++ {
+		var value any = float64(i) + 0.125
+		if i == 1 {
+			value = nil
+		}
+		tw.Append([]any{"태그-<1>", ts.Add(time.Duration(i) * 12 * time.Hour), value})
+		if tw.RequirePageRender() {
+			tw.Render()
+			tw.PauseAndWait()
+		}
+	}
+	if _, err := tw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("invalid xlsx file: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], _ = io.ReadAll(r)
+		r.Close()
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing %s", name)
+		}
+	}
+	sheet := xlsxSheet{}
+	if err := xml.Unmarshal(files["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatalf("invalid sheet: %v", err)
+	}
+	if len(sheet.Panes) != 1 || sheet.Panes[0].YSplit != "1" || sheet.Panes[0].State != "frozen" {
+		t.Errorf("header row should be frozen, got %+v", sheet.Panes)
+	}
+	if len(sheet.Cols) != 3 || sheet.Cols[0].Width != "8" || sheet.Cols[1].Width != "25" {
+		t.Errorf("unexpected column widths %+v", sheet.Cols)
+	}
+	if len(sheet.Rows) != nRows+1 {
+		t.Fatalf("expected %d rows, got %d", nRows+1, len(sheet.Rows))
+	}
+	header := sheet.Rows[0].Cells
+	if len(header) != 3 || header[2].Ref != "C1" || header[2].Text != "VALUE" || header[2].Style != "2" {
+		t.Errorf("unexpected header %+v", header)
+	}
+	first := sheet.Rows[1].Cells
+	if len(first) != 3 || first[0].Type != "inlineStr" || first[0].Text != "태그-<1>" {
+		t.Errorf("unexpected string cell %+v", first[0])
+	}
+	if first[1].Ref != "B2" || first[1].Style != "1" || first[1].Value != "45659" {
+		t.Errorf("unexpected datetime cell %+v", first[1])
+	}
+	if first[2].Type != "" || first[2].Value != "0.13" {
+		t.Errorf("unexpected number cell %+v", first[2])
+	}
+	if second := sheet.Rows[2].Cells; len(second) != 2 || second[1].Value != "45659.5" {
+		t.Errorf("null value should be an empty cell, got %+v", second)
+	}
+	if last := sheet.Rows[nRows].Cells; last[0].Ref != "A2501" || !strings.HasPrefix(last[2].Value, "2499.1") {
+		t.Errorf("unexpected last row %+v", last)
+	}
+
+	if got := xlsxColumnName(0) + xlsxColumnName(25) + xlsxColumnName(26) + xlsxColumnName(701) + xlsxColumnName(702); got != "AZAAZZAAA" {
+		t.Errorf("unexpected column names %s", got)
+	}
+}
+
+func TestTableXLSXAbort(t *testing.T) {
+	var buf bytes.Buffer
+	tbl, err := Table(TableOption{Format: "xlsx", Header: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tbl.(*TableWriter)
+	tw.SetOutput(map[string]any{"writer": &buf})
+	tw.AppendHeader(table.Row{"NAME", "VALUE"})
+	tw.Append([]any{"a", 1.0})
+	tw.Render()
+	tmp := tw.xlsx.tmp.Name()
+	if _, err := os.Stat(tmp); err != nil {
+		t.Fatalf("temporary file should exist, %v", err)
+	}
+	tw.Abort()
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("temporary file should be removed, %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("aborted table should not write, got %d bytes", buf.Len())
+	}
+}
+
+func TestTableXLSXRowLimit(t *testing.T) {
+	var buf bytes.Buffer
+	tbl, err := Table(TableOption{Format: "xlsx", Header: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tbl.(*TableWriter)
+	tw.SetOutput(map[string]any{"writer": &buf})
+	tw.AppendHeader(table.Row{"VALUE"})
+	tw.Append([]any{1})
+	tw.Render()
+	// skip to the last row of the sheet
+	tw.xlsx.rowNum = xlsxMaxRows - 1
+	tw.Append([]any{2})
+	tw.Append([]any{3})
+	tw.Render()
+	if _, err := tw.Close(); err == nil || err.Error() != "xlsx sheet is limited to 1048576 rows" {
+		t.Errorf("expected the row limit error, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package pretty
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// RenderXML writes the rows as the "row" elements of a "result" document,
+// the values are "col" elements with the "name" attribute if the header is enabled.
+// The first render writes the start of the document, the end is written by Close().
+func (tw *TableWriter) RenderXML() string {
+	var out strings.Builder
+	rows := tw.rawRows
+	if tw.renderCount == 0 {
+		out.WriteString(xml.Header)
+		out.WriteString("<result>\n")
+		if tw.header {
+			kinds := tw.columnKinds()
+			out.WriteString("  <columns>")
+			for i, h := range tw.columnHeaders(rows) {
+				out.WriteString("<column")
+				if kind := kindAt(kinds, i); kind != "" {
+					out.WriteString(` type="`)
+					xml.EscapeText(&out, []byte(kind))
+					out.WriteString(`"`)
+				}
+				out.WriteString(">")
+				xml.EscapeText(&out, []byte(h))
+				out.WriteString("</column>")
+			}
+			out.WriteString("</columns>\n")
+		}
+		out.WriteString("  <rows>\n")
+	}
+	tw.renderRowsXML(&out, rows)
+	ret := out.String()
+	if tw.output != nil {
+		tw.output.Write([]byte(ret))
+	}
+	return ret
+}
+
+func (tw *TableWriter) renderRowsXML(out *strings.Builder, rows []table.Row) {
+	headers := tw.columnHeaders(rows)
+	for _, row := range rows {
+		out.WriteString("    <row>")
+		for i, col := range row {
+			out.WriteString("<col")
+			if tw.header && i < len(headers) {
+				out.WriteString(` name="`)
+				xml.EscapeText(out, []byte(headers[i]))
+				out.WriteString(`"`)
+			}
+			if col == nil {
+				out.WriteString(` null="true"`)
+			}
+			out.WriteString(">")
+			xml.EscapeText(out, []byte(tw.xmlText(col)))
+			out.WriteString("</col>")
+		}
+		out.WriteString("</row>\n")
+	}
+}
+
+// closeXML writes the end of the document.
+func (tw *TableWriter) closeXML() string {
+	ret := "  </rows>\n</result>\n"
+	if tw.output != nil {
+		tw.output.Write([]byte(ret))
+	}
+	return ret
+}
+
+// xmlText returns the text of the value, nil is the null value.
+func (tw *TableWriter) xmlText(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return tw.nullValue
+	case string:
+		return val
+	case []byte:
+		if tw.blobEncoding == "HEX" {
+			return hex.EncodeToString(val)
+		}
+		return base64.StdEncoding.EncodeToString(val)
+	case time.Time:
+		return fmt.Sprint(tw.formatTime(val))
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'g', -1, 32)
+	default:
+		return fmt.Sprint(val)
+	}
+}
@@ -0,0 +1,76 @@
+package pretty
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+func TestTableXML(t *testing.T) {
+	var buf bytes.Buffer
+	tbl, err := Table(TableOption{Format: "xml", Header: true, Rownum: true, Timeformat: "s", NullValue: "NULL", Precision: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tbl.(*TableWriter)
+	tw.SetOutput(map[string]any{"writer": &buf})
+	tw.AppendHeader(table.Row{"NAME", "TIME", "VALUE"})
+	tw.SetColumnTypes([]string{"string", "datetime", "double"})
+	ts := time.Unix(1700000000, 0)
+	const nRows = 2500
+	for i := 0; i < nRows; i++ {
+		var value any = float64(i) + 0.5
+		if i == 1 {
+			value = nil
+		}
+		tw.Append([]any{fmt.Sprintf("<tag%d> & \"x\"", i), ts, value})
+		if tw.RequirePageRender() {
+			tw.Render()
+			tw.PauseAndWait()
+		}
+	}
+	if _, err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := struct {
+		Columns []struct {
+			Type string `xml:"type,attr"`
+			Name string `xml:",chardata"`
+		} `xml:"columns>column"`
+		Rows []struct {
+			Cols []struct {
+				Name  string `xml:"name,attr"`
+				Null  bool   `xml:"null,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"col"`
+		} `xml:"rows>row"`
+	}{}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid xml: %v", err)
+	}
+	if len(doc.Columns) != 4 || doc.Columns[0].Name != "ROWNUM" || doc.Columns[0].Type != "long" || doc.Columns[2].Type != "datetime" {
+		t.Errorf("unexpected columns %+v", doc.Columns)
+	}
+	if len(doc.Rows) != nRows {
+		t.Fatalf("expected %d rows, got %d", nRows, len(doc.Rows))
+	}
+	first := doc.Rows[0].Cols
+	if len(first) != 4 || first[0].Value != "1" || first[1].Name != "NAME" || first[1].Value != `<tag0> & "x"` || first[2].Value != "1700000000" || first[3].Value != "0.5" {
+		t.Errorf("unexpected row %+v", first)
+	}
+	if null := doc.Rows[1].Cols[3]; !null.Null || null.Value != "NULL" {
+		t.Errorf("unexpected null %+v", null)
+	}
+	if last := doc.Rows[nRows-1].Cols; last[0].Value != "2500" || last[3].Value != "2499.5" {
+		t.Errorf("unexpected last row %+v", last)
+	}
+	if !strings.HasSuffix(buf.String(), "  </rows>\n</result>\n") {
+		t.Errorf("unexpected end of document %q", buf.String()[buf.Len()-40:])
+	}
+}
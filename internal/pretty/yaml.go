@@ -0,0 +1,150 @@
+package pretty
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// RenderYAML writes the rows as a YAML document of "columns" and "rows",
+// a row is a mapping of the column names if the header is enabled, otherwise a sequence.
+// The first render writes the keys of the document, the next renders continue the "rows" sequence,
+// and Close() writes the empty sequence if no rows are rendered.
+func (tw *TableWriter) RenderYAML() string {
+	var out strings.Builder
+	rows := tw.rawRows
+	if tw.renderCount == 0 {
+		if tw.header {
+			out.WriteString("columns: [")
+			for i, h := range tw.columnHeaders(rows) {
+				if i > 0 {
+					out.WriteString(", ")
+				}
+				writeYAMLKey(&out, h)
+			}
+			out.WriteString("]\n")
+		}
+		out.WriteString("rows:\n")
+	}
+	tw.renderRowsYAML(&out, rows)
+	ret := out.String()
+	if tw.output != nil {
+		tw.output.Write([]byte(ret))
+	}
+	return ret
+}
+
+// closeYAML writes the empty sequence of "rows" if there is no row.
+func (tw *TableWriter) closeYAML() string {
+	if tw.rowCount > 0 {
+		return ""
+	}
+	ret := "  []\n"
+	if tw.output != nil {
+		tw.output.Write([]byte(ret))
+	}
+	return ret
+}
+
+func (tw *TableWriter) renderRowsYAML(out *strings.Builder, rows []table.Row) {
+	headers := tw.columnHeaders(rows)
+	for _, row := range rows {
+		if !tw.header {
+			out.WriteString("  - [")
+			for i, col := range row {
+				if i > 0 {
+					out.WriteString(", ")
+				}
+				tw.writeYAMLValue(out, col)
+			}
+			out.WriteString("]\n")
+			continue
+		}
+		for i, col := range row {
+			if i == 0 {
+				out.WriteString("  - ")
+			} else {
+				out.WriteString("    ")
+			}
+			writeYAMLKey(out, headers[i])
+			out.WriteString(": ")
+			tw.writeYAMLValue(out, col)
+			out.WriteRune('\n')
+		}
+	}
+}
+
+var yamlPlainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// writeYAMLKey writes the name as a plain scalar if it is an identifier, otherwise a quoted string.
+func writeYAMLKey(out *strings.Builder, name string) {
+	switch strings.ToLower(name) {
+	case "null", "true", "false", "yes", "no", "on", "off", "y", "n":
+		writeJSONString(out, name)
+		return
+	}
+	if yamlPlainKey.MatchString(name) {
+		out.WriteString(name)
+	} else {
+		writeJSONString(out, name)
+	}
+}
+
+// writeYAMLValue writes the value as a YAML scalar, the strings are double-quoted
+// and nil is the null value if it is a YAML null (e.g. NULL, null, ~), otherwise the quoted null value.
+func (tw *TableWriter) writeYAMLValue(out *strings.Builder, v any) {
+	switch val := v.(type) {
+	case nil:
+		switch tw.nullValue {
+		case "":
+			out.WriteString("null")
+		case "~", "null", "Null", "NULL":
+			out.WriteString(tw.nullValue)
+		default:
+			writeJSONString(out, tw.nullValue)
+		}
+	case string:
+		writeJSONString(out, val)
+	case []byte:
+		if tw.blobEncoding == "HEX" {
+			writeJSONString(out, hex.EncodeToString(val))
+		} else {
+			writeJSONString(out, base64.StdEncoding.EncodeToString(val))
+		}
+	case time.Time:
+		switch tv := tw.formatTime(val).(type) {
+		case string:
+			writeJSONString(out, tv)
+		default:
+			fmt.Fprint(out, tv)
+		}
+	case float64:
+		writeYAMLFloat(out, val, 64)
+	case float32:
+		writeYAMLFloat(out, float64(val), 32)
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		fmt.Fprint(out, val)
+	default:
+		writeJSONString(out, fmt.Sprint(val))
+	}
+}
+
+func writeYAMLFloat(out *strings.Builder, f float64, bitSize int) {
+	switch {
+	case math.IsNaN(f):
+		out.WriteString(".nan")
+	case math.IsInf(f, 1):
+		out.WriteString(".inf")
+	case math.IsInf(f, -1):
+		out.WriteString("-.inf")
+	default:
+		out.WriteString(strconv.FormatFloat(f, 'g', -1, bitSize))
+	}
+}
@@ -0,0 +1,97 @@
+package pretty
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"gopkg.in/yaml.v3"
+)
+
+func TestTableYAML(t *testing.T) {
+	ts := time.Unix(1700000000, 123000000)
+	render := func(opt TableOption) string {
+		var buf bytes.Buffer
+		tbl, err := Table(opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tw := tbl.(*TableWriter)
+		tw.SetOutput(map[string]any{"writer": &buf})
+		tw.AppendHeader(table.Row{"NAME", "TIME", "VALUE", "null"})
+		tw.SetColumnTypes([]string{"string", "datetime", "double", "string"})
+		tw.Append([]any{"say \"hi\"\n", ts, 1.5, nil})
+		tw.Render()
+		tw.PauseAndWait()
+		tw.Append([]any{"yes", ts, math.NaN(), "x"})
+		if _, err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	got := render(TableOption{Format: "yaml", Header: true, Rownum: true, Timeformat: "ms", NullValue: "NULL", Precision: -1})
+	expect := "columns: [ROWNUM, NAME, TIME, VALUE, \"null\"]\n" +
+		"rows:\n" +
+		"  - ROWNUM: 1\n" +
+		"    NAME: \"say \\\"hi\\\"\\n\"\n" +
+		"    TIME: 1700000000123\n" +
+		"    VALUE: 1.5\n" +
+		"    \"null\": NULL\n" +
+		"  - ROWNUM: 2\n" +
+		"    NAME: \"yes\"\n" +
+		"    TIME: 1700000000123\n" +
+		"    VALUE: .nan\n" +
+		"    \"null\": \"x\"\n"
+	if got != expect {
+		t.Fatalf("unexpected yaml\n%s", got)
+	}
+	doc := struct {
+		Columns []string         `yaml:"columns"`
+		Rows    []map[string]any `yaml:"rows"`
+	}{}
+	if err := yaml.Unmarshal([]byte(got), &doc); err != nil {
+		t.Fatalf("invalid yaml: %v", err)
+	}
+	if len(doc.Columns) != 5 || len(doc.Rows) != 2 || doc.Rows[0]["null"] != nil || doc.Rows[1]["NAME"] != "yes" || doc.Rows[0]["TIME"] != 1700000000123 {
+		t.Errorf("unexpected document %+v", doc)
+	}
+
+	got = render(TableOption{Format: "yaml", Timeformat: "rfc3339", Tz: "UTC", NullValue: "-", Precision: -1})
+	expect = "rows:\n" +
+		"  - [\"say \\\"hi\\\"\\n\", \"2023-11-14T22:13:20.123Z\", 1.5, \"-\"]\n" +
+		"  - [\"yes\", \"2023-11-14T22:13:20.123Z\", .nan, \"x\"]\n"
+	if got != expect {
+		t.Fatalf("unexpected yaml without header\n%s", got)
+	}
+
+	// the first page has no rows
+	for _, n := range []int{0, 2} {
+		var buf bytes.Buffer
+		tbl, err := Table(TableOption{Format: "yaml", Header: true, NullValue: "NULL", Precision: -1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tw := tbl.(*TableWriter)
+		tw.SetOutput(map[string]any{"writer": &buf})
+		tw.AppendHeader(table.Row{"NAME"})
+		tw.Render()
+		for i := 0; i < n; i++ {
+			tw.Append([]any{"a"})
+		}
+		if _, err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		doc := struct {
+			Rows []map[string]any `yaml:"rows"`
+		}{}
+		if err := yaml.Unmarshal(buf.Bytes(), &doc); err != nil {
+			t.Fatalf("invalid yaml of %d rows: %v\n%s", n, err, buf.String())
+		}
+		if doc.Rows == nil || len(doc.Rows) != n {
+			t.Errorf("expected %d rows, got %+v\n%s", n, doc.Rows, buf.String())
+		}
+	}
+}
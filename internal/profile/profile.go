@@ -0,0 +1,263 @@
+package profile
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/machbase/jsh/engine"
+)
+
+func Module(rt *goja.Runtime, module *goja.Object) {
+	exports := module.Get("exports").(*goja.Object)
+	exports.Set("list", List)
+	exports.Set("load", loadObject)
+	exports.Set("save", saveObject)
+	exports.Set("remove", Remove)
+}
+
+// Profile is a named set of connection settings.
+// Each profile is stored as a secret box under the user's config directory,
+// so the password never hits the disk in plain text.
+type Profile struct {
+	Name     string
+	Host     string
+	User     string
+	Password string
+	Volumes  engine.FSTabs
+	Env      map[string]any
+}
+
+// configDir is replaceable for testing
+var configDir = os.UserConfigDir
+
+var nameRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.@-]*$`)
+
+const boxExt = ".box"
+
+// Dir returns the directory where the profiles are stored.
+func Dir() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "neo-shell", "profiles"), nil
+}
+
+func profilePath(name string) (string, error) {
+	if !nameRegexp.MatchString(name) {
+		return "", fmt.Errorf("invalid profile name %q", name)
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+boxExt), nil
+}
+
+// List returns the names of the stored profiles in alphabetical order.
+func List() ([]string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	ret := []string{}
+	for _, ent := range entries {
+		if ent.IsDir() || !strings.HasSuffix(ent.Name(), boxExt) {
+			continue
+		}
+		ret = append(ret, strings.TrimSuffix(ent.Name(), boxExt))
+	}
+	slices.Sort(ret)
+	return ret, nil
+}
+
+// Load reads the profile of the given name.
+func Load(name string) (*Profile, error) {
+	path, err := profilePath(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("profile %q not found", name)
+		}
+		return nil, err
+	}
+	// read from a copy, so that the stored profile is never touched by the reader
+	tmp, err := os.CreateTemp("", "neo-shell-profile-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if err := copyFile(tmp, path); err != nil {
+		return nil, err
+	}
+
+	conf := engine.Config{}
+	if err := engine.ReadSecretBox(tmp.Name(), &conf); err != nil {
+		return nil, fmt.Errorf("profile %q: %w", name, err)
+	}
+	ret := &Profile{
+		Name:    name,
+		Volumes: conf.FSTabs,
+		Env:     map[string]any{},
+	}
+	for k, v := range conf.Env {
+		switch k {
+		case "NEOSHELL_HOST":
+			ret.Host, _ = v.(string)
+		case "NEOSHELL_USER":
+			ret.User, _ = v.(string)
+		case "NEOSHELL_PASSWORD":
+			ret.Password = SecureValue(v)
+		default:
+			ret.Env[k] = v
+		}
+	}
+	return ret, nil
+}
+
+// Save stores the profile, it replaces the existing profile of the same name.
+func Save(p *Profile) error {
+	path, err := profilePath(p.Name)
+	if err != nil {
+		return err
+	}
+	env := map[string]any{}
+	for k, v := range p.Env {
+		env[k] = v
+	}
+	env["NEOSHELL_HOST"] = p.Host
+	env["NEOSHELL_USER"] = p.User
+	env["NEOSHELL_PASSWORD"] = engine.SecureString(p.Password)
+
+	box, err := engine.NewSecretBox(engine.Config{
+		FSTabs: p.Volumes,
+		Env:    env,
+	})
+	if err != nil {
+		return err
+	}
+	defer os.Remove(box.FilePath())
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// write to a temporary file of the same directory and rename it,
+	// so that the existing profile is kept if the write fails.
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if err := copyFile(f, box.FilePath()); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// Remove deletes the profile of the given name.
+func Remove(name string) error {
+	path, err := profilePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("profile %q not found", name)
+		}
+		return err
+	}
+	return nil
+}
+
+// SecureValue returns the plain text of the value that is either
+// engine.SecureString or string.
+func SecureValue(v any) string {
+	switch s := v.(type) {
+	case engine.SecureString:
+		return s.Value()
+	case string:
+		return s
+	default:
+		return ""
+	}
+}
+
+// copyFile copies the content of src into dst and closes dst.
+func copyFile(dst *os.File, src string) error {
+	defer dst.Close()
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if _, err := io.Copy(dst, r); err != nil {
+		return err
+	}
+	return dst.Sync()
+}
+
+// loadObject is the javascript binding of Load().
+// The password is kept as engine.SecureString, so that it is carried
+// in the environment variables without being exposed.
+func loadObject(name string) (map[string]any, error) {
+	p, err := Load(name)
+	if err != nil {
+		return nil, err
+	}
+	volumes := []string{}
+	for _, tab := range p.Volumes {
+		volumes = append(volumes, fmt.Sprintf("%s=%s", tab.MountPoint, tab.Source))
+	}
+	return map[string]any{
+		"name":     p.Name,
+		"host":     p.Host,
+		"user":     p.User,
+		"password": engine.SecureString(p.Password),
+		"volumes":  volumes,
+		"env":      p.Env,
+	}, nil
+}
+
+type profileObject struct {
+	Host     string         `json:"host"`
+	User     string         `json:"user"`
+	Password any            `json:"password"`
+	Volumes  []string       `json:"volumes"`
+	Env      map[string]any `json:"env"`
+}
+
+// saveObject is the javascript binding of Save().
+func saveObject(name string, obj profileObject) error {
+	p := &Profile{
+		Name:     name,
+		Host:     obj.Host,
+		User:     obj.User,
+		Password: SecureValue(obj.Password),
+		Env:      obj.Env,
+	}
+	for _, v := range obj.Volumes {
+		if err := p.Volumes.Set(v); err != nil {
+			return err
+		}
+	}
+	return Save(p)
+}
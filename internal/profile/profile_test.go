@@ -0,0 +1,73 @@
+package profile
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/machbase/jsh/engine"
+)
+
+func TestProfile(t *testing.T) {
+	dir := t.TempDir()
+	saved := configDir
+	configDir = func() (string, error) { return dir, nil }
+	t.Cleanup(func() { configDir = saved })
+
+	p := &Profile{
+		Name:     "plant-1",
+		Host:     "10.0.0.5:5654",
+		User:     "sys",
+		Password: "manager",
+		Volumes:  engine.FSTabs{{MountPoint: "/data", Source: "/var/data"}},
+		Env:      map[string]any{"LANG": "ko"},
+	}
+	if err := Save(p); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := Save(&Profile{Name: "1-invalid"}); err == nil {
+		t.Fatalf("Save should fail with invalid name")
+	}
+
+	names, err := List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if !slices.Equal(names, []string{"plant-1"}) {
+		t.Fatalf("List returns %v", names)
+	}
+
+	got, err := Load("plant-1")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got.Host != p.Host || got.User != p.User || got.Password != p.Password {
+		t.Errorf("Load returns %+v", got)
+	}
+	if len(got.Volumes) != 1 || got.Volumes[0].MountPoint != "/data" || got.Volumes[0].Source != "/var/data" {
+		t.Errorf("Load returns volumes %+v", got.Volumes)
+	}
+	if got.Env["LANG"] != "ko" {
+		t.Errorf("Load returns env %+v", got.Env)
+	}
+
+	// Save replaces the existing profile without leaving the temporary file
+	p.Host = "10.0.0.6:5654"
+	if err := Save(p); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if got, err := Load("plant-1"); err != nil || got.Host != p.Host {
+		t.Errorf("Load returns %+v, %v", got, err)
+	}
+	profiles, _ := Dir()
+	if entries, err := os.ReadDir(profiles); err != nil || len(entries) != 1 {
+		t.Errorf("expected only the profile in %s, got %v %v", profiles, entries, err)
+	}
+
+	if err := Remove("plant-1"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, err := Load("plant-1"); err == nil {
+		t.Fatalf("Load should fail after Remove")
+	}
+}
@@ -2,13 +2,17 @@ package session
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
+	"os"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/dop251/goja"
 )
@@ -19,7 +23,18 @@ func Module(rt *goja.Runtime, module *goja.Object) {
 	exports.Set("setHttpToken", SetHttpToken)
 	exports.Set("getHttpAccessToken", GetHttpAccessToken)
 	exports.Set("getHttpRefreshToken", GetHttpRefreshToken)
+	exports.Set("refreshHttpToken", RefreshHttpToken)
 	exports.Set("getMachCliConfig", GetMachCliConfig)
+	// multiple sessions
+	exports.Set("DEFAULT_NAME", DefaultName)
+	exports.Set("open", openObject)
+	exports.Set("TLS_ENV", TLSEnv)
+	exports.Set("get", Get)
+	exports.Set("use", Use)
+	exports.Set("active", Active)
+	exports.Set("resolve", Resolve)
+	exports.Set("list", List)
+	exports.Set("close", Close)
 }
 
 type Config struct {
@@ -27,22 +42,208 @@ type Config struct {
 	User     string
 	Password string
 
-	httpProto string
-	httpHost  string
-	httpPort  int
+	TLS TLSConfig
 
-	machHost string
-	machPort int
+	httpClient *http.Client
+	httpProto  string
+	httpHost   string
+	httpPort   int
 
-	accessToken  string
-	refreshToken string
+	// mach servers in the order of preference
+	machCandidates []HostPort
+
+	tokens *tokenStore
+	name   string
 }
 
-var defaultSession Config
+// DefaultName is the name of the session that is configured by Configure().
+const DefaultName = "default"
+
+var (
+	sessionsMu sync.RWMutex
+	sessions   = map[string]*Config{}
+	activeName = DefaultName
+	// unconfigured is used when no session is configured yet
+	unconfigured = &Config{tokens: &tokenStore{}}
+)
 
+// Configure opens the default session and makes it active.
 func Configure(c Config) error {
+	if _, err := Open(DefaultName, c); err != nil {
+		return err
+	}
+	return Use(DefaultName)
+}
+
+// Open logs in to the server and registers the session with the name,
+// the existing session of the same name is replaced.
+func Open(name string, c Config) (*Config, error) {
+	if name == "" {
+		return nil, fmt.Errorf("session name is required")
+	}
+	if err := c.open(); err != nil {
+		return nil, err
+	}
+	c.name = name
+	sessionsMu.Lock()
+	sessions[name] = &c
+	sessionsMu.Unlock()
+	return &c, nil
+}
+
+// Get returns the opened session of the name.
+func Get(name string) (*Config, error) {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+	if s, ok := sessions[name]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("session '%s' is not opened", name)
+}
+
+// Use makes the session of the name active.
+func Use(name string) error {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if _, ok := sessions[name]; !ok {
+		return fmt.Errorf("session '%s' is not opened", name)
+	}
+	activeName = name
+	return nil
+}
+
+// Active returns the active session.
+func Active() *Config {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+	if s, ok := sessions[activeName]; ok {
+		return s
+	}
+	return unconfigured
+}
+
+// Resolve returns the session that v refers to,
+// v can be a session, a name of session or nil for the active session.
+func Resolve(v any) (*Config, error) {
+	switch s := v.(type) {
+	case nil:
+		return Active(), nil
+	case *Config:
+		return s, nil
+	case string:
+		if s == "" {
+			return Active(), nil
+		}
+		return Get(s)
+	default:
+		return nil, fmt.Errorf("invalid session %v", v)
+	}
+}
+
+type SessionInfo struct {
+	Name   string `json:"name"`
+	Server string `json:"server"`
+	User   string `json:"user"`
+	Active bool   `json:"active"`
+}
+
+// List returns the opened sessions in the order of the name.
+func List() []SessionInfo {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+	ret := []SessionInfo{}
+	for name, s := range sessions {
+		ret = append(ret, SessionInfo{Name: name, Server: s.Server, User: s.User, Active: name == activeName})
+	}
+	slices.SortFunc(ret, func(a, b SessionInfo) int { return strings.Compare(a.Name, b.Name) })
+	return ret
+}
+
+// Close removes the session of the name,
+// if it is the active session, the default session becomes active.
+func Close(name string) error {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if _, ok := sessions[name]; !ok {
+		return fmt.Errorf("session '%s' is not opened", name)
+	}
+	delete(sessions, name)
+	if activeName == name {
+		activeName = DefaultName
+	}
+	return nil
+}
+
+type OpenOptions struct {
+	Server             string `json:"server"`
+	User               string `json:"user"`
+	Password           any    `json:"password"`
+	Tls                bool   `json:"tls"`
+	CaCert             string `json:"caCert"`
+	ClientCert         string `json:"clientCert"`
+	ClientKey          string `json:"clientKey"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"`
+	MachTls            bool   `json:"machTls"`
+	// Env has the TLS options as the environment variables of TLSEnv, e.g. the env of a profile,
+	// the options above take precedence.
+	Env map[string]any `json:"env"`
+}
+
+// openObject is the javascript binding of Open().
+func openObject(name string, opts OpenOptions) (*Config, error) {
+	c := Config{
+		Server:   opts.Server,
+		User:     opts.User,
+		Password: secureValue(opts.Password),
+		TLS: TLSConfig{
+			Tls:                opts.Tls,
+			CaCert:             opts.CaCert,
+			ClientCert:         opts.ClientCert,
+			ClientKey:          opts.ClientKey,
+			InsecureSkipVerify: opts.InsecureSkipVerify,
+			MachTls:            opts.MachTls,
+		},
+	}
+	c.TLS.FromEnv(opts.Env)
+	if c.User == "" {
+		c.User = "sys"
+	}
+	if c.Password == "" {
+		c.Password = "manager"
+	}
+	return Open(name, c)
+}
+
+// secureValue returns the plain text of the password,
+// that can be a string or a secure string which hides its value.
+func secureValue(v any) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case interface{ Value() string }:
+		return s.Value()
+	default:
+		return ""
+	}
+}
+
+// Name returns the name of the session.
+func (c *Config) Name() string {
+	return c.name
+}
+
+func (c *Config) open() error {
+	c.httpProto = "http"
+	c.httpClient = http.DefaultClient
+	if c.TLS.Enabled() {
+		tlsConf, err := c.TLS.ClientConfig()
+		if err != nil {
+			return err
+		}
+		c.httpProto = "https"
+		c.httpClient = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConf}}
+	}
 	if h, p, err := net.SplitHostPort(c.Server); err == nil {
-		c.httpProto = "http"
 		c.httpHost = h
 		c.httpPort, err = strconv.Atoi(p)
 		if err != nil {
@@ -52,29 +253,14 @@ func Configure(c Config) error {
 		return err
 	}
 
-	loginPayload := map[string]string{
-		"loginName": c.User,
-		"password":  c.Password,
-	}
-	b, _ := json.Marshal(loginPayload)
-	path := fmt.Sprintf("%s://%s:%d/web/api/login", c.httpProto, c.httpHost, c.httpPort)
-	rsp, err := http.Post(path, "application/json", bytes.NewBuffer(b))
-	if err != nil {
+	c.tokens = &tokenStore{}
+	if err := c.login(); err != nil {
 		return err
 	}
-	defer rsp.Body.Close()
-	if rsp.StatusCode != http.StatusOK {
-		return fmt.Errorf("login failed with status code %d", rsp.StatusCode)
-	}
-	var rspData struct {
-		AccessToken  string `json:"accessToken"`
-		RefreshToken string `json:"refreshToken"`
-	}
-	if err := json.NewDecoder(rsp.Body).Decode(&rspData); err != nil {
+	accessToken, err := c.AccessToken()
+	if err != nil {
 		return err
 	}
-	c.accessToken = rspData.AccessToken
-	c.refreshToken = rspData.RefreshToken
 
 	rpcPayload := map[string]any{
 		"jsonrpc": "2.0",
@@ -82,15 +268,15 @@ func Configure(c Config) error {
 		"params":  []any{"mach"},
 		"id":      1,
 	}
-	b, _ = json.Marshal(rpcPayload)
+	b, _ := json.Marshal(rpcPayload)
 	rpcPath := fmt.Sprintf("%s://%s:%d/web/api/rpc", c.httpProto, c.httpHost, c.httpPort)
 	rpcReq, err := http.NewRequest("POST", rpcPath, bytes.NewBuffer(b))
 	if err != nil {
 		return err
 	}
 	rpcReq.Header.Set("Content-Type", "application/json")
-	rpcReq.Header.Set("Authorization", "Bearer "+c.accessToken)
-	rpcRsp, err := http.DefaultClient.Do(rpcReq)
+	rpcReq.Header.Set("Authorization", "Bearer "+accessToken)
+	rpcRsp, err := c.httpClient.Do(rpcReq)
 	if err != nil {
 		return err
 	}
@@ -150,13 +336,140 @@ func Configure(c Config) error {
 		}
 		return 0
 	})
-	c.machHost = candidates[0].Host
-	c.machPort = candidates[0].Port
-
-	defaultSession = c
+	if len(candidates) == 0 {
+		return fmt.Errorf("no mach service port is available on %s", c.Server)
+	}
+	c.machCandidates = candidates
 	return nil
 }
 
+// TLSConfig holds the client side TLS settings.
+// The certificate and key generated by 'key gen' (<name>_cert.pem, <name>_key.pem)
+// can be used as the client identity as they are.
+type TLSConfig struct {
+	Tls                bool   `json:"tls"`
+	CaCert             string `json:"caCert,omitempty"`
+	ClientCert         string `json:"clientCert,omitempty"`
+	ClientKey          string `json:"clientKey,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+	// MachTls connects to the mach port over TLS as well,
+	// it requires a TLS terminator in front of the mach port.
+	MachTls bool `json:"machTls,omitempty"`
+}
+
+// Enabled returns true if any of the TLS options of the http connection is set.
+func (tc TLSConfig) Enabled() bool {
+	return tc.Tls || tc.CaCert != "" || tc.ClientCert != "" || tc.InsecureSkipVerify
+}
+
+// TLSEnv are the environment variables of the TLS options,
+// that pass the options of the active session to the child processes.
+var TLSEnv = []string{
+	"NEOSHELL_TLS",
+	"NEOSHELL_CA_CERT",
+	"NEOSHELL_CLIENT_CERT",
+	"NEOSHELL_CLIENT_KEY",
+	"NEOSHELL_INSECURE_SKIP_VERIFY",
+	"NEOSHELL_MACH_TLS",
+}
+
+// FromEnv fills the options that are not set yet from the environment variables of TLSEnv.
+func (tc *TLSConfig) FromEnv(env map[string]any) {
+	str := func(name string) string {
+		if v, ok := env[name].(string); ok {
+			return v
+		}
+		return ""
+	}
+	if !tc.Tls {
+		tc.Tls, _ = strconv.ParseBool(str("NEOSHELL_TLS"))
+	}
+	if tc.CaCert == "" {
+		tc.CaCert = str("NEOSHELL_CA_CERT")
+	}
+	if tc.ClientCert == "" {
+		tc.ClientCert = str("NEOSHELL_CLIENT_CERT")
+	}
+	if tc.ClientKey == "" {
+		tc.ClientKey = str("NEOSHELL_CLIENT_KEY")
+	}
+	if !tc.InsecureSkipVerify {
+		tc.InsecureSkipVerify, _ = strconv.ParseBool(str("NEOSHELL_INSECURE_SKIP_VERIFY"))
+	}
+	if !tc.MachTls {
+		tc.MachTls, _ = strconv.ParseBool(str("NEOSHELL_MACH_TLS"))
+	}
+}
+
+// SetEnv sets the environment variables of TLSEnv by the options.
+func (tc TLSConfig) SetEnv(env map[string]any) {
+	if tc.MachTls {
+		env["NEOSHELL_MACH_TLS"] = "true"
+	}
+	if !tc.Enabled() {
+		return
+	}
+	env["NEOSHELL_TLS"] = "true"
+	env["NEOSHELL_CA_CERT"] = tc.CaCert
+	env["NEOSHELL_CLIENT_CERT"] = tc.ClientCert
+	env["NEOSHELL_CLIENT_KEY"] = tc.KeyFile()
+	env["NEOSHELL_INSECURE_SKIP_VERIFY"] = strconv.FormatBool(tc.InsecureSkipVerify)
+}
+
+// KeyFile returns the client key file, if it is not specified
+// it is derived from the client certificate file name that 'key gen' produces.
+func (tc TLSConfig) KeyFile() string {
+	if tc.ClientKey != "" || tc.ClientCert == "" {
+		return tc.ClientKey
+	}
+	if strings.HasSuffix(tc.ClientCert, "_cert.pem") {
+		return strings.TrimSuffix(tc.ClientCert, "_cert.pem") + "_key.pem"
+	}
+	return ""
+}
+
+// ClientConfig builds *tls.Config from the settings.
+func (tc TLSConfig) ClientConfig() (*tls.Config, error) {
+	ret := &tls.Config{
+		InsecureSkipVerify: tc.InsecureSkipVerify,
+	}
+	if tc.CaCert != "" {
+		pem, err := os.ReadFile(tc.CaCert)
+		if err != nil {
+			return nil, fmt.Errorf("ca-cert: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca-cert: no certificate found in %s", tc.CaCert)
+		}
+		ret.RootCAs = pool
+	}
+	if tc.ClientCert != "" {
+		keyFile := tc.KeyFile()
+		if keyFile == "" {
+			return nil, fmt.Errorf("client-key is required for %s", tc.ClientCert)
+		}
+		cert, err := tls.LoadX509KeyPair(tc.ClientCert, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("client-cert: %w", err)
+		}
+		ret.Certificates = []tls.Certificate{cert}
+	}
+	return ret, nil
+}
+
+// pemContent returns the content of the file, or empty string if path is empty.
+func pemContent(path string) string {
+	if path == "" {
+		return ""
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
 type HostPort struct {
 	Host string `json:"host"`
 	Port int    `json:"port"`
@@ -180,43 +493,112 @@ type HttpConfig struct {
 	Port     int    `json:"port"`
 	User     string `json:"user"`
 	Password string `json:"password"`
+	// TLS options in the PEM contents
+	Ca                 string `json:"ca,omitempty"`
+	Cert               string `json:"cert,omitempty"`
+	Key                string `json:"key,omitempty"`
+	RejectUnauthorized bool   `json:"rejectUnauthorized"`
 }
 
 func GetHttpConfig() HttpConfig {
-	return HttpConfig{
-		Protocol: "http:",
-		Host:     defaultSession.httpHost,
-		Port:     defaultSession.httpPort,
-		User:     defaultSession.User,
-		Password: defaultSession.Password,
+	return Active().GetHttpConfig()
+}
+
+func (c *Config) GetHttpConfig() HttpConfig {
+	ret := HttpConfig{
+		Protocol:           "http:",
+		Host:               c.httpHost,
+		Port:               c.httpPort,
+		User:               c.User,
+		Password:           c.Password,
+		RejectUnauthorized: true,
 	}
+	if c.httpProto == "https" {
+		tc := c.TLS
+		ret.Protocol = "https:"
+		ret.Ca = pemContent(tc.CaCert)
+		ret.Cert = pemContent(tc.ClientCert)
+		ret.Key = pemContent(tc.KeyFile())
+		ret.RejectUnauthorized = !tc.InsecureSkipVerify
+	}
+	return ret
 }
 
 func SetHttpToken(accessToken string, refreshToken string) {
-	defaultSession.accessToken = accessToken
-	defaultSession.refreshToken = refreshToken
+	Active().SetHttpToken(accessToken, refreshToken)
+}
+
+func (c *Config) SetHttpToken(accessToken string, refreshToken string) {
+	c.tokens.set(accessToken, refreshToken)
+}
+
+// GetHttpAccessToken returns the access token that is valid at least for a while,
+// it refreshes the token in advance if it is about to expire.
+func GetHttpAccessToken() (string, error) {
+	return Active().AccessToken()
 }
 
-func GetHttpAccessToken() string {
-	return defaultSession.accessToken
+func (c *Config) GetHttpAccessToken() (string, error) {
+	return c.AccessToken()
 }
 
 func GetHttpRefreshToken() string {
-	return defaultSession.refreshToken
+	return Active().GetHttpRefreshToken()
+}
+
+func (c *Config) GetHttpRefreshToken() string {
+	return c.tokens.refreshToken()
+}
+
+// RefreshHttpToken refreshes the access token that is rejected by the server,
+// it returns the new access token.
+func RefreshHttpToken(rejected string) (string, error) {
+	return Active().RefreshAccessToken(rejected)
+}
+
+func (c *Config) RefreshHttpToken(rejected string) (string, error) {
+	return c.RefreshAccessToken(rejected)
 }
 
 type MachCliConfig struct {
-	Host     string `json:"host"`
-	Port     int    `json:"port"`
-	User     string `json:"user"`
-	Password string `json:"password"`
+	Host            string     `json:"host"`
+	Port            int        `json:"port"`
+	User            string     `json:"user"`
+	Password        string     `json:"password"`
+	AlternativeHost string     `json:"alternativeHost,omitempty"`
+	AlternativePort int        `json:"alternativePort,omitempty"`
+	Candidates      []HostPort `json:"candidates,omitempty"`
+	// TLS options
+	Tls                bool   `json:"tls"`
+	CaCert             string `json:"caCert,omitempty"`
+	ClientCert         string `json:"clientCert,omitempty"`
+	ClientKey          string `json:"clientKey,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+	MachTls            bool   `json:"machTls,omitempty"`
 }
 
 func GetMachCliConfig() MachCliConfig {
-	return MachCliConfig{
-		Host:     defaultSession.machHost,
-		Port:     defaultSession.machPort,
-		User:     defaultSession.User,
-		Password: defaultSession.Password,
+	return Active().GetMachCliConfig()
+}
+
+func (c *Config) GetMachCliConfig() MachCliConfig {
+	tc := c.TLS
+	ret := MachCliConfig{
+		User:               c.User,
+		Password:           c.Password,
+		Tls:                tc.Enabled(),
+		CaCert:             tc.CaCert,
+		ClientCert:         tc.ClientCert,
+		ClientKey:          tc.KeyFile(),
+		InsecureSkipVerify: tc.InsecureSkipVerify,
+		MachTls:            tc.MachTls,
+	}
+	if len(c.machCandidates) > 0 {
+		ret.Host, ret.Port = c.machCandidates[0].Host, c.machCandidates[0].Port
+		ret.Candidates = slices.Clone(c.machCandidates)
+	}
+	if len(c.machCandidates) > 1 {
+		ret.AlternativeHost, ret.AlternativePort = c.machCandidates[1].Host, c.machCandidates[1].Port
 	}
+	return ret
 }
@@ -0,0 +1,343 @@
+package session
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer mimics the login, relogin and getServicePorts api of machbase-neo.
+type testServer struct {
+	machAddrs []string
+	tokenTTL  time.Duration // 0 means the tokens have no expiry
+
+	mu       sync.Mutex
+	seq      int
+	valid    map[string]bool
+	logins   int
+	relogins int
+}
+
+func newTestServer(t *testing.T, machAddrs ...string) *testServer {
+	t.Helper()
+	return &testServer{machAddrs: machAddrs, valid: map[string]bool{}}
+}
+
+// issue returns a new pair of tokens, it should be called with the lock held.
+func (ts *testServer) issue() map[string]any {
+	ts.seq++
+	claims := map[string]any{"sub": "sys", "seq": ts.seq}
+	if ts.tokenTTL > 0 {
+		claims["exp"] = nowFunc().Add(ts.tokenTTL).Unix()
+	}
+	b, _ := json.Marshal(claims)
+	access := "header." + base64.RawURLEncoding.EncodeToString(b) + ".sig"
+	refresh := fmt.Sprintf("refresh-%d", ts.seq)
+	ts.valid[access], ts.valid[refresh] = true, true
+	return map[string]any{"success": true, "accessToken": access, "refreshToken": refresh}
+}
+
+func (ts *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	var req map[string]any
+	json.NewDecoder(r.Body).Decode(&req)
+	switch r.URL.Path {
+	case "/web/api/login":
+		if req["loginName"] != "sys" || req["password"] != "manager" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		ts.logins++
+		json.NewEncoder(w).Encode(ts.issue())
+	case "/web/api/relogin":
+		refresh, _ := req["refreshToken"].(string)
+		if !ts.valid[refresh] {
+			json.NewEncoder(w).Encode(map[string]any{"success": false, "reason": "invalid refresh token"})
+			return
+		}
+		delete(ts.valid, refresh)
+		ts.relogins++
+		json.NewEncoder(w).Encode(ts.issue())
+	case "/web/api/rpc":
+		if !ts.valid[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")] {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		result := []map[string]string{}
+		for _, addr := range ts.machAddrs {
+			result = append(result, map[string]string{"Service": "mach", "Address": addr})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": 1, "result": result})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	svr := httptest.NewServer(newTestServer(t, "tcp://192.168.1.10:5656", "tcp://127.0.0.1:5656"))
+	defer svr.Close()
+
+	err := Configure(Config{
+		Server:   strings.TrimPrefix(svr.URL, "http://"),
+		User:     "sys",
+		Password: "manager",
+	})
+	if err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	hc := GetHttpConfig()
+	if hc.Protocol != "http:" || hc.Host != "127.0.0.1" {
+		t.Errorf("unexpected http config %+v", hc)
+	}
+	mc := GetMachCliConfig()
+	if mc.Host != "127.0.0.1" || mc.Port != 5656 || mc.Tls {
+		t.Errorf("unexpected mach config %+v", mc)
+	}
+	if mc.AlternativeHost != "192.168.1.10" || mc.AlternativePort != 5656 || len(mc.Candidates) != 2 {
+		t.Errorf("unexpected mach candidates %+v", mc)
+	}
+	if tok, err := GetHttpAccessToken(); err != nil || tok == "" || GetHttpRefreshToken() != "refresh-1" {
+		t.Errorf("unexpected tokens %q, %q, %v", tok, GetHttpRefreshToken(), err)
+	}
+}
+
+func TestConfigureNoMachPort(t *testing.T) {
+	svr := httptest.NewServer(newTestServer(t))
+	defer svr.Close()
+
+	_, err := Open("nomach", Config{Server: strings.TrimPrefix(svr.URL, "http://"), User: "sys", Password: "manager"})
+	if err == nil || !strings.Contains(err.Error(), "no mach service port") {
+		t.Fatalf("expected error of no mach port, got %v", err)
+	}
+	if _, err := Get("nomach"); err == nil {
+		t.Errorf("failed session should not be registered")
+	}
+}
+
+func TestSessions(t *testing.T) {
+	svr1 := httptest.NewServer(newTestServer(t, "tcp://127.0.0.1:5656"))
+	defer svr1.Close()
+	svr2 := httptest.NewServer(newTestServer(t, "tcp://127.0.0.1:5757"))
+	defer svr2.Close()
+
+	if err := Configure(Config{Server: strings.TrimPrefix(svr1.URL, "http://"), User: "sys", Password: "manager"}); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	other, err := openObject("other", OpenOptions{Server: strings.TrimPrefix(svr2.URL, "http://")})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	defer Close("other")
+	if other.Name() != "other" || other.GetMachCliConfig().Port != 5757 {
+		t.Errorf("unexpected session %q %+v", other.Name(), other.GetMachCliConfig())
+	}
+	// opening a session does not change the active session
+	if Active().Name() != DefaultName || GetMachCliConfig().Port != 5656 {
+		t.Errorf("unexpected active session %q", Active().Name())
+	}
+	if s, err := Resolve("other"); err != nil || s != other {
+		t.Errorf("Resolve by name failed: %v", err)
+	}
+	if s, err := Resolve(nil); err != nil || s.Name() != DefaultName {
+		t.Errorf("Resolve active failed: %v", err)
+	}
+	if _, err := Resolve("unknown"); err == nil {
+		t.Errorf("Resolve should fail for the unknown session")
+	}
+
+	if err := Use("other"); err != nil {
+		t.Fatalf("Use failed: %v", err)
+	}
+	if GetMachCliConfig().Port != 5757 {
+		t.Errorf("active session is not switched")
+	}
+	list := List()
+	if len(list) != 2 || list[0].Name != DefaultName || list[0].Active || list[1].Name != "other" || !list[1].Active {
+		t.Errorf("unexpected list %+v", list)
+	}
+
+	// closing the active session falls back to the default session
+	if err := Close("other"); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if Active().Name() != DefaultName {
+		t.Errorf("expected default session, got %q", Active().Name())
+	}
+	if err := Use("other"); err == nil {
+		t.Errorf("Use should fail for the closed session")
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	now := time.Now()
+	nowFunc = func() time.Time { return now }
+	defer func() { nowFunc = time.Now }()
+
+	ts := newTestServer(t, "tcp://127.0.0.1:5656")
+	ts.tokenTTL = 5 * time.Minute
+	svr := httptest.NewServer(ts)
+	defer svr.Close()
+
+	err := Configure(Config{Server: strings.TrimPrefix(svr.URL, "http://"), User: "sys", Password: "manager"})
+	if err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	first, _ := GetHttpAccessToken()
+	if exp := jwtExpiry(first); !exp.Equal(time.Unix(now.Add(ts.tokenTTL).Unix(), 0)) {
+		t.Fatalf("unexpected expiry %v", exp)
+	}
+
+	// still valid, no refresh
+	now = now.Add(4 * time.Minute)
+	if tok, _ := GetHttpAccessToken(); tok != first || ts.relogins != 0 {
+		t.Fatalf("token should not be refreshed yet")
+	}
+
+	// about to expire, the concurrent callers refresh only once
+	now = now.Add(45 * time.Second)
+	var wg sync.WaitGroup
+	tokens := make([]string, 10)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], _ = GetHttpAccessToken()
+		}(i)
+	}
+	wg.Wait()
+	if ts.relogins != 1 {
+		t.Fatalf("expected 1 relogin, got %d", ts.relogins)
+	}
+	for _, tok := range tokens {
+		if tok == first || tok != tokens[0] {
+			t.Fatalf("unexpected refreshed token %q", tok)
+		}
+	}
+
+	// the rejected token is refreshed only once
+	second := tokens[0]
+	third, err := RefreshHttpToken(second)
+	if err != nil || third == second {
+		t.Fatalf("RefreshHttpToken failed: %q, %v", third, err)
+	}
+	if tok, _ := RefreshHttpToken(second); tok != third || ts.relogins != 2 {
+		t.Fatalf("refreshed token should be reused, relogins=%d", ts.relogins)
+	}
+
+	// the refresh token is rejected, it falls back to login
+	ts.mu.Lock()
+	ts.valid = map[string]bool{}
+	ts.mu.Unlock()
+	now = now.Add(time.Hour)
+	if tok, err := GetHttpAccessToken(); err != nil || tok == third || ts.logins != 2 {
+		t.Fatalf("expected re-login, token=%q err=%v logins=%d", tok, err, ts.logins)
+	}
+}
+
+func TestConfigureTLS(t *testing.T) {
+	svr := httptest.NewTLSServer(newTestServer(t, "tcp://127.0.0.1:5656"))
+	defer svr.Close()
+
+	// save the server certificate as the CA certificate
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: svr.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPem, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	server := strings.TrimPrefix(svr.URL, "https://")
+	// without the CA certificate, it fails to verify the server
+	if err := Configure(Config{Server: server, User: "sys", Password: "manager", TLS: TLSConfig{Tls: true}}); err == nil {
+		t.Fatalf("Configure should fail with unknown authority")
+	}
+
+	err := Configure(Config{
+		Server:   server,
+		User:     "sys",
+		Password: "manager",
+		TLS:      TLSConfig{Tls: true, CaCert: caFile},
+	})
+	if err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	hc := GetHttpConfig()
+	if hc.Protocol != "https:" || hc.Ca != string(caPem) || !hc.RejectUnauthorized {
+		t.Errorf("unexpected http config %+v", hc)
+	}
+	// the mach port is connected in plain unless MachTls is set
+	if mc := GetMachCliConfig(); !mc.Tls || mc.MachTls || mc.CaCert != caFile {
+		t.Errorf("unexpected mach config %+v", mc)
+	}
+
+	err = Configure(Config{
+		Server:   server,
+		User:     "sys",
+		Password: "manager",
+		TLS:      TLSConfig{InsecureSkipVerify: true},
+	})
+	if err != nil {
+		t.Fatalf("Configure with insecure-skip-verify failed: %v", err)
+	}
+	if hc := GetHttpConfig(); hc.Protocol != "https:" || hc.RejectUnauthorized {
+		t.Errorf("unexpected http config %+v", hc)
+	}
+}
+
+func TestTLSConfigKeyFile(t *testing.T) {
+	tests := []struct {
+		conf TLSConfig
+		want string
+	}{
+		{TLSConfig{}, ""},
+		{TLSConfig{ClientCert: "/keys/dev_cert.pem"}, "/keys/dev_key.pem"},
+		{TLSConfig{ClientCert: "/keys/dev_cert.pem", ClientKey: "/keys/other.pem"}, "/keys/other.pem"},
+		{TLSConfig{ClientCert: "/keys/client.crt"}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.conf.KeyFile(); got != tt.want {
+			t.Errorf("KeyFile() of %+v = %q, want %q", tt.conf, got, tt.want)
+		}
+	}
+}
+
+func TestTLSConfigEnv(t *testing.T) {
+	tc := TLSConfig{CaCert: "/keys/ca.pem"}
+	tc.FromEnv(map[string]any{
+		"NEOSHELL_TLS":         "true",
+		"NEOSHELL_CA_CERT":     "/keys/other.pem",
+		"NEOSHELL_CLIENT_CERT": "/keys/dev_cert.pem",
+		"NEOSHELL_MACH_TLS":    "true",
+	})
+	// the option that is set takes precedence
+	if !tc.Tls || tc.CaCert != "/keys/ca.pem" || tc.ClientCert != "/keys/dev_cert.pem" || !tc.MachTls || tc.InsecureSkipVerify {
+		t.Errorf("unexpected options %+v", tc)
+	}
+
+	env := map[string]any{}
+	tc.SetEnv(env)
+	if env["NEOSHELL_TLS"] != "true" || env["NEOSHELL_CLIENT_KEY"] != "/keys/dev_key.pem" || env["NEOSHELL_MACH_TLS"] != "true" {
+		t.Errorf("unexpected env %+v", env)
+	}
+	for k := range env {
+		if !slices.Contains(TLSEnv, k) {
+			t.Errorf("%s is not in TLSEnv", k)
+		}
+	}
+
+	env = map[string]any{}
+	TLSConfig{}.SetEnv(env)
+	if len(env) != 0 {
+		t.Errorf("expected no env without TLS, got %+v", env)
+	}
+}
@@ -0,0 +1,166 @@
+package session
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenRefreshMargin is how long before the expiry the access token is refreshed.
+const tokenRefreshMargin = 30 * time.Second
+
+// nowFunc is replaceable for testing
+var nowFunc = time.Now
+
+// tokenStore keeps the tokens of a session,
+// the mutex serializes the login and refresh of the concurrent callers.
+type tokenStore struct {
+	mu      sync.Mutex
+	access  string
+	refresh string
+	expiry  time.Time // zero if the token does not tell its expiry
+}
+
+func (ts *tokenStore) set(access, refresh string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.setLocked(access, refresh)
+}
+
+func (ts *tokenStore) setLocked(access, refresh string) {
+	ts.access = access
+	ts.refresh = refresh
+	ts.expiry = jwtExpiry(access)
+}
+
+func (ts *tokenStore) refreshToken() string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.refresh
+}
+
+// expiring returns true if the access token needs to be refreshed.
+func (ts *tokenStore) expiring() bool {
+	if ts.access == "" {
+		return true
+	}
+	if ts.expiry.IsZero() {
+		return false
+	}
+	return nowFunc().Add(tokenRefreshMargin).After(ts.expiry)
+}
+
+// jwtExpiry returns the 'exp' claim of the JWT token without verifying it.
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(claims.Exp), 0)
+}
+
+// AccessToken returns the valid access token, it logs in or refreshes the token if necessary.
+func (c *Config) AccessToken() (string, error) {
+	if c.tokens == nil {
+		return "", errors.New("session is not configured")
+	}
+	c.tokens.mu.Lock()
+	defer c.tokens.mu.Unlock()
+	if c.tokens.expiring() {
+		if err := c.reloginLocked(); err != nil {
+			return "", err
+		}
+	}
+	return c.tokens.access, nil
+}
+
+// RefreshAccessToken refreshes the access token that was rejected by the server.
+// If another caller has already refreshed it, the current token is returned as it is.
+func (c *Config) RefreshAccessToken(rejected string) (string, error) {
+	if c.tokens == nil {
+		return "", errors.New("session is not configured")
+	}
+	c.tokens.mu.Lock()
+	defer c.tokens.mu.Unlock()
+	if rejected == "" || rejected == c.tokens.access {
+		if err := c.reloginLocked(); err != nil {
+			return "", err
+		}
+	}
+	return c.tokens.access, nil
+}
+
+func (c *Config) login() error {
+	c.tokens.mu.Lock()
+	defer c.tokens.mu.Unlock()
+	return c.loginLocked()
+}
+
+func (c *Config) loginLocked() error {
+	access, refresh, err := c.postToken("login", map[string]string{
+		"loginName": c.User,
+		"password":  c.Password,
+	})
+	if err != nil {
+		return err
+	}
+	c.tokens.setLocked(access, refresh)
+	return nil
+}
+
+// reloginLocked refreshes the tokens with the refresh token,
+// it logs in again if the refresh token is not available or rejected.
+func (c *Config) reloginLocked() error {
+	if c.tokens.refresh != "" {
+		access, refresh, err := c.postToken("relogin", map[string]string{
+			"refreshToken": c.tokens.refresh,
+		})
+		if err == nil {
+			c.tokens.setLocked(access, refresh)
+			return nil
+		}
+	}
+	return c.loginLocked()
+}
+
+func (c *Config) postToken(api string, payload map[string]string) (string, string, error) {
+	b, _ := json.Marshal(payload)
+	path := fmt.Sprintf("%s://%s:%d/web/api/%s", c.httpProto, c.httpHost, c.httpPort, api)
+	rsp, err := c.httpClient.Post(path, "application/json", bytes.NewBuffer(b))
+	if err != nil {
+		return "", "", err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("%s failed with status code %d", api, rsp.StatusCode)
+	}
+	var rspData struct {
+		Success      bool   `json:"success"`
+		Reason       string `json:"reason"`
+		AccessToken  string `json:"accessToken"`
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.NewDecoder(rsp.Body).Decode(&rspData); err != nil {
+		return "", "", err
+	}
+	if !rspData.Success {
+		return "", "", fmt.Errorf("%s failed: %s", api, rspData.Reason)
+	}
+	return rspData.AccessToken, rspData.RefreshToken, nil
+}
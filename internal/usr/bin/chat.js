@@ -75,10 +75,10 @@ function loop(chat, ws) {
     }
 }
 
-const chat = new Chat({ host: '192.168.1.165', port: 5654 });
+const chat = new Chat();
 chat.login()
     .then((wsUrl) => {
-        const ws = new WebSocket(wsUrl);
+        const ws = new WebSocket(wsUrl, chat.tlsOptions());
         ws.on('open', () => {
             setImmediate(() => {
                 chat.connected = true;
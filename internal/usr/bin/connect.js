@@ -3,14 +3,46 @@
 const process = require('process');
 const env = process.env;
 
-const user = env.get('NEOSHELL_USER');
-const password = env.get('NEOSHELL_PASSWORD');
+// connect [profile]
+//   without profile, it re-connects to the current server as another user.
+//   with profile, it switches to the server and user of the saved profile.
+const profileName = process.argv.length > 2 ? process.argv[2] : '';
 
-env.set('NEOSHELL_USER', null);
-env.set('NEOSHELL_PASSWORD', null);
+const saved = {};
+const overrides = {
+    NEOSHELL_USER: null,
+    NEOSHELL_PASSWORD: null,
+};
+
+if (profileName) {
+    let p;
+    try {
+        p = require('@jsh/profile').load(profileName);
+    } catch (err) {
+        console.println('Error:', err.message);
+        process.exit(1);
+    }
+    // the volumes are mounted only when neo-shell starts
+    if (p.volumes.length > 0) {
+        console.println(`Error: profile '${profileName}' has volumes (${p.volumes.join(', ')}), start with 'neo-shell --profile ${profileName}' to mount them`);
+        process.exit(1);
+    }
+    overrides.NEOSHELL_HOST = p.host;
+    overrides.NEOSHELL_USER = p.user;
+    overrides.NEOSHELL_PASSWORD = p.password;
+    for (const k of Object.keys(p.env)) {
+        overrides[k] = p.env[k];
+    }
+}
+
+for (const k of Object.keys(overrides)) {
+    saved[k] = env.get(k);
+    env.set(k, overrides[k]);
+}
 
 process.exec('neo-shell')
 
 console.println("disconnected from neo-shell");
-env.set('NEOSHELL_USER', user);
-env.set('NEOSHELL_PASSWORD', password);
\ No newline at end of file
+for (const k of Object.keys(saved)) {
+    env.set(k, saved[k]);
+}
@@ -0,0 +1,194 @@
+'use strict';
+
+const process = require('process');
+const parseArgs = require('util/parseArgs');
+const session = require('@jsh/session');
+const profile = require('@jsh/profile');
+const machcli = require('/usr/lib/machcli');
+const pretty = require('/usr/lib/pretty');
+
+const options = {
+    help: { type: 'boolean', short: 'h', description: 'Show this help message', default: false },
+    srcSession: { type: 'string', short: 's', description: "session or profile name of the source (default: active session)", default: '' },
+    dstSession: { type: 'string', short: 'd', description: "session or profile name of the destination (default: active session)", default: '' },
+    where: { type: 'string', short: 'w', description: "condition to filter the source rows", default: '' },
+    from: { type: 'string', description: "copy the rows of which time is equal to or after", default: '' },
+    to: { type: 'string', description: "copy the rows of which time is before", default: '' },
+    timeColumn: { type: 'string', description: "time column for --from and --to (default: basetime column or _ARRIVAL_TIME)", default: '' },
+    timeformat: { type: 'string', short: 't', description: "time format of --from and --to [ns|us|ms|s|<timeformat>] (default: RFC3339)", default: '' },
+    tz: { type: 'string', description: "time zone for handling datetime (default: time zone)", default: 'local' },
+    batch: { type: 'integer', short: 'b', description: "number of rows to flush at once", default: 10000 },
+    create: { type: 'boolean', description: "create the destination table if it does not exist", default: true },
+    silent: { type: 'boolean', description: "suppress progress output", default: false },
+}
+
+const positionals = [
+    { name: 'source', type: 'string', description: 'source table name or SELECT query' },
+    { name: 'destination', type: 'string', optional: true, description: 'destination table name (default: same as the source table)' },
+];
+
+let showHelp = true;
+let config = {};
+let source = '';
+let destination = '';
+try {
+    const parsed = parseArgs(process.argv.slice(2), {
+        options,
+        allowPositionals: true,
+        allowNegative: true,
+        positionals: positionals
+    });
+    config = parsed.values;
+    source = parsed.namedPositionals.source;
+    destination = parsed.namedPositionals.destination;
+    showHelp = config.help
+}
+catch (err) {
+    console.println(err.message);
+}
+
+const isQuery = source && /^\s*select\s/i.test(source);
+if (!destination && !isQuery) {
+    destination = source;
+}
+
+if (showHelp || !source || !destination) {
+    console.println(parseArgs.formatHelp({
+        usage: 'Usage: copy [options] <source> [destination]',
+        options,
+        positionals: positionals
+    }));
+    process.exit(showHelp ? 0 : 1);
+}
+
+// openSession returns the session of the name,
+// it opens the session with the profile of the same name if it is not opened yet,
+// the TLS options of the profile are in its env, e.g. NEOSHELL_TLS.
+function openSession(name) {
+    if (!name) {
+        return session.active();
+    }
+    try {
+        return session.get(name);
+    } catch {
+        const p = profile.load(name);
+        return session.open(name, { server: p.host, user: p.user, password: p.password, env: p.env });
+    }
+}
+
+// sourceQuery returns the SELECT statement and its parameters to read the source rows.
+function sourceQuery(srcConn, srcDb) {
+    if (isQuery) {
+        if (config.where || config.from || config.to) {
+            throw new Error('--where, --from and --to are not allowed with a query source');
+        }
+        return { sqlText: source, params: [], schema: null };
+    }
+    const schema = machcli.queryTableSchema(srcConn, srcDb.normalizeTableName(source));
+    if (!schema) {
+        throw new Error(`Table '${source}' not found`);
+    }
+    const conds = [];
+    const params = [];
+    if (config.where) {
+        conds.push(`(${config.where})`);
+    }
+    if (config.from || config.to) {
+        let timeColumn = config.timeColumn;
+        if (!timeColumn) {
+            const basetime = schema.columns.find((c) => c.flag & machcli.ColumnFlag.Basetime);
+            timeColumn = basetime ? basetime.name : '_ARRIVAL_TIME';
+        }
+        if (config.from) {
+            conds.push(`${timeColumn} >= ?`);
+            params.push(pretty.parseTime(config.from, config.timeformat, config.tz));
+        }
+        if (config.to) {
+            conds.push(`${timeColumn} < ?`);
+            params.push(pretty.parseTime(config.to, config.timeformat, config.tz));
+        }
+    }
+    let sqlText = `SELECT ${schema.columns.map((c) => c.name).join(', ')} FROM ${source}`;
+    if (conds.length > 0) {
+        sqlText += ` WHERE ${conds.join(' AND ')}`;
+    }
+    return { sqlText, params, schema };
+}
+
+let srcDb, srcConn, dstDb, dstConn, rows, appender, tracker;
+let exitCode = 0;
+try {
+    const srcSession = openSession(config.srcSession);
+    const dstSession = openSession(config.dstSession);
+    srcDb = new machcli.Client({ session: srcSession });
+    srcConn = srcDb.connect();
+    dstDb = new machcli.Client({ session: dstSession });
+    dstConn = dstDb.connect();
+
+    if (!isQuery && srcSession.name() === dstSession.name()
+        && srcDb.normalizeTableName(source).join('.') === dstDb.normalizeTableName(destination).join('.')) {
+        throw new Error('source and destination are the same table');
+    }
+
+    const { sqlText, params, schema } = sourceQuery(srcConn, srcDb);
+
+    if (!machcli.queryTableSchema(dstConn, dstDb.normalizeTableName(destination))) {
+        if (!config.create || !schema) {
+            throw new Error(`Table '${destination}' not found in the destination`);
+        }
+        dstConn.exec(machcli.createTableSQL(destination, schema));
+    }
+
+    let total = 0;
+    if (!config.silent) {
+        try {
+            const r = srcConn.queryRow(`SELECT COUNT(*) AS CNT FROM (${sqlText})`, ...params);
+            total = r.CNT || 0;
+        } catch {
+            total = 0;
+        }
+        tracker = pretty.Progress({ showPercentage: true }).tracker({
+            label: `Copying ${source} into ${destination}`,
+            total: total,
+        });
+    }
+
+    rows = srcConn.query(sqlText, ...params);
+    appender = dstConn.append(destination).withInputColumns(...rows.columnNames);
+
+    let nRows = 0;
+    let pending = 0;
+    for (const row of rows) {
+        appender.append(...rows.columnNames.map((name) => row[name]));
+        nRows++;
+        pending++;
+        if (pending >= config.batch) {
+            appender.flush();
+            tracker && tracker.increment(pending);
+            pending = 0;
+        }
+    }
+    const result = appender.close();
+    appender = null;
+    if (tracker) {
+        tracker.increment(pending);
+        tracker.markAsDone();
+    }
+    setTimeout(() => {
+        console.println(`Copy ${pretty.Ints(nRows)} rows completed. success: ${pretty.Ints(result[0])}, fail: ${pretty.Ints(result[1])}`);
+    }, 100);
+} catch (err) {
+    tracker && tracker.markAsErrored();
+    console.println(`Error during copy: ${err.message}`);
+    exitCode = 1;
+} finally {
+    appender && appender.close();
+    rows && rows.close();
+    srcConn && srcConn.close();
+    srcDb && srcDb.close();
+    dstConn && dstConn.close();
+    dstDb && dstDb.close();
+}
+if (exitCode !== 0) {
+    process.exit(exitCode);
+}
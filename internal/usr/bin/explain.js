@@ -42,15 +42,24 @@ if (showHelp || (!args.sql) || args.sql.length === 0) {
 explain(config, args.sql.join(' '), config.full);
 
 function explain(config, sqlText, full = false) {
-    let db, conn;
+    let db, conn, stop;
     try {
         db = new Client(config);
+        stop = db.cancelOnInterrupt();
         conn = db.connect();
         let result = conn.explain(sqlText, full);
+        if (db.cancelled()) {
+            throw new Error('query cancelled');
+        }
         console.println(result);
     } catch (err) {
-        console.println("Error: ", err.message);
+        if (db && db.cancelled()) {
+            console.println('query cancelled');
+        } else {
+            console.println("Error: ", err.message);
+        }
     } finally {
+        stop && stop();
         conn && conn.close();
         db && db.close();
     }
@@ -3,18 +3,30 @@
 const process = require('process');
 const parseArgs = require('util/parseArgs');
 const machcli = require('/usr/lib/machcli');
+const pretty = require('/usr/lib/pretty');
+const fs = require('fs');
+const path = require('path');
 
 const options = {
     help: { type: 'boolean', short: 'h', description: 'Show this help message', default: false },
     output: { type: 'string', short: 'o', description: "output file (default:'-' stdout)", default: '-' },
     compress: { type: 'string', description: "compression type (none, gzip)", default: 'none' },
-    format: { type: 'string', short: 'f', description: "output format (box, csv, tsv, json, ndjson)", default: 'csv' },
+    format: { type: 'string', short: 'f', description: "output format (box, csv, tsv, json, ndjson, yaml, xml, parquet, xlsx, insert)", default: 'csv' },
     timeformat: { type: 'string', short: 't', description: "time format [ns|us|ms|s|<timeformat>]", default: 'ns' },
     tz: { type: 'string', description: "time zone for handling datetime (default: time zone)", default: 'local' },
     precision: { type: 'integer', short: 'p', description: "set precision of float value to force round", default: -1 },
     header: { type: 'boolean', description: "print header", default: false },
     nullValue: { type: 'string', description: "string to represent null values", default: '' },
     silent: { type: 'boolean', description: "suppress progress output", default: false },
+    tag: { type: 'string', description: "comma separated tag names to export from a tag table", default: '' },
+    from: { type: 'string', description: "export the rows of which time is equal to or after (RFC3339 or epoch in --timeformat)", default: '' },
+    to: { type: 'string', description: "export the rows of which time is before (RFC3339 or epoch in --timeformat)", default: '' },
+    rollup: { type: 'string', description: "export the rollup of the interval, e.g. 10s, 1m, 1h, 1d", default: '' },
+    agg: { type: 'string', description: "aggregation of --rollup (avg, min, max, sum, count, sumsq)", default: 'avg' },
+    split: { type: 'string', description: "write a file per tag or per day of --tz (tag, day)", default: '' },
+    chunkRows: { type: 'integer', description: "write numbered parts of the rows of a log or tag table (0: disable)", default: 0 },
+    createTable: { type: 'boolean', description: "write CREATE TABLE before the rows of the insert format", default: false },
+    checkpoint: { type: 'string', description: "manifest file of the parts to continue an interrupted --chunk-rows export", default: '' },
 }
 
 const positionals = [
@@ -48,44 +60,524 @@ if (showHelp || (!tableName) || tableName.length === 0) {
     process.exit(showHelp ? 0 : 1);
 }
 
-let args = [
-    '--output', config.output,
-    '--compress', config.compress,
-    '--format', config.format,
-    '--timeformat', config.timeformat,
-    '--tz', config.tz,
-    '--precision', config.precision,
-    '--null-value', config.nullValue,
-    '--no-rownum',
-    '--no-pause',
-    '--no-footer',
-];
+const epochUnits = ['ns', 'us', 'ms', 's'];
 
-if (config.header) {
-    args.push('--header');
-} else {
-    args.push('--no-header');
+// parseTimeArg parses --from and --to, the epoch numbers are in the unit of --timeformat.
+function parseTimeArg(name, value) {
+    if (/^\d+$/.test(value)) {
+        const unit = epochUnits.includes(config.timeformat) ? config.timeformat : 'ns';
+        return pretty.parseTime(value, unit, config.tz);
+    }
+    for (const layout of ['', '2006-01-02', config.timeformat]) {
+        if (epochUnits.includes(layout)) {
+            continue;
+        }
+        try {
+            return pretty.parseTime(value, layout, config.tz);
+        } catch {
+            // try the next layout
+        }
+    }
+    throw new Error(`invalid time of --${name} '${value}'`);
 }
 
-if (config.silent) {
-    args.push('--progress', '-1');
-} else {
-    let db, conn;
+// timeLiteral returns the SQL expression of the time,
+// the nanoseconds are built as a string as they do not fit in a javascript number.
+function timeLiteral(t) {
+    return `FROM_TIMESTAMP(${t.unix()}${String(t.nanosecond()).padStart(9, '0')})`;
+}
+
+// rangeConditions returns the conditions of the time column for the range [from, to).
+function rangeConditions(column, from, to) {
+    const conds = [];
+    if (from) {
+        conds.push(`${column} >= ${timeLiteral(from)}`);
+    }
+    if (to) {
+        conds.push(`${column} < ${timeLiteral(to)}`);
+    }
+    return conds;
+}
+
+function quote(s) {
+    return `'${String(s).replace(/'/g, "''")}'`;
+}
+
+// parseRollup returns the unit and the number of ROLLUP() and the interval in milliseconds.
+function parseRollup(value) {
+    const m = /^(\d+)\s*(s|sec|m|min|h|hour|d|day)$/i.exec(value.trim());
+    if (!m || parseInt(m[1]) <= 0) {
+        throw new Error(`invalid --rollup '${value}', e.g. 10s, 1m, 1h, 1d`);
+    }
+    const units = {
+        s: ['sec', 1000], sec: ['sec', 1000],
+        m: ['min', 60000], min: ['min', 60000],
+        h: ['hour', 3600000], hour: ['hour', 3600000],
+        d: ['day', 86400000], day: ['day', 86400000],
+    };
+    const [unit, ms] = units[m[2].toLowerCase()];
+    const n = parseInt(m[1]);
+    return { unit, n, millis: n * ms };
+}
+
+// splitPath inserts the key of the split into the file name, e.g. out.csv.gz to out.<key>.csv.gz.
+function splitPath(output, key) {
+    key = String(key).replace(/[^A-Za-z0-9._-]/g, '_');
+    const slash = output.lastIndexOf('/') + 1;
+    const dot = output.indexOf('.', slash + 1);
+    if (dot < 0) {
+        return `${output}.${key}`;
+    }
+    return `${output.substring(0, dot)}.${key}${output.substring(dot)}`;
+}
+
+// queryStats returns the row count and the time range of the tags from V$<table>_STAT.
+function queryStats(conn, names, tags) {
+    let sqlText = `SELECT NAME, ROW_COUNT, MIN_TIME, MAX_TIME FROM ${names[0]}.${names[1]}.V$${names[2]}_STAT`;
+    if (tags.length > 0) {
+        sqlText += ` WHERE NAME IN (${tags.map(() => '?').join(',')})`;
+    }
+    const stats = [];
+    let rows;
+    try {
+        rows = conn.query(sqlText, ...tags);
+        for (const row of rows) {
+            stats.push({ name: row.NAME, count: row.ROW_COUNT || 0, min: row.MIN_TIME, max: row.MAX_TIME });
+        }
+    } finally {
+        rows && rows.close();
+    }
+    return stats;
+}
+
+// estimate returns the expected number of the rows of the time range [from, to),
+// assuming the rows of a tag are evenly distributed between its min and max time.
+function estimate(stats, from, to, rollup) {
+    let total = 0;
+    for (const s of stats) {
+        if (!s.count || !s.min || !s.max) {
+            continue;
+        }
+        const min = s.min.unixMilli();
+        const max = s.max.unixMilli() + 1;
+        const lo = from ? Math.max(min, from.unixMilli()) : min;
+        const hi = to ? Math.min(max, to.unixMilli()) : max;
+        if (hi <= lo) {
+            continue;
+        }
+        let n = Math.ceil(s.count * (hi - lo) / (max - min));
+        if (rollup) {
+            n = Math.min(n, Math.ceil((hi - lo) / rollup.millis) + 1);
+        }
+        total += n;
+    }
+    return total;
+}
+
+function parseTags() {
+    return config.tag.split(',').map((s) => s.trim()).filter((s) => s.length > 0);
+}
+
+// logConditions returns the conditions of --from and --to of a table other than a tag table,
+// on the basetime column or _ARRIVAL_TIME.
+function logConditions(schema) {
+    const basetime = schema.columns.find((c) => c.flag & machcli.ColumnFlag.Basetime);
+    return rangeConditions(basetime ? basetime.name : '_ARRIVAL_TIME',
+        config.from ? parseTimeArg('from', config.from) : null,
+        config.to ? parseTimeArg('to', config.to) : null);
+}
+
+// tagJobs returns the queries and the output files to export a tag table.
+function tagJobs(conn, names, schema) {
+    const nameCol = schema.columns.find((c) => c.flag & machcli.ColumnFlag.TagName);
+    const timeCol = schema.columns.find((c) => c.flag & machcli.ColumnFlag.Basetime);
+    const valueCol = schema.columns.find((c) => c.flag & machcli.ColumnFlag.Summarized);
+    const tags = parseTags();
+    const from = config.from ? parseTimeArg('from', config.from) : null;
+    const to = config.to ? parseTimeArg('to', config.to) : null;
+
+    let rollup = null;
+    let agg = '';
+    if (config.rollup) {
+        rollup = parseRollup(config.rollup);
+        agg = config.agg.toUpperCase();
+        if (!['AVG', 'MIN', 'MAX', 'SUM', 'COUNT', 'SUMSQ'].includes(agg)) {
+            throw new Error(`invalid --agg '${config.agg}'`);
+        }
+        if (!valueCol) {
+            throw new Error(`table '${tableName}' has no summarized column for --rollup`);
+        }
+    }
+
+    const stats = queryStats(conn, names, tags);
+    const query = (tags, from, to) => {
+        const conds = rangeConditions(timeCol.name, from, to);
+        if (tags.length > 0) {
+            conds.unshift(`${nameCol.name} IN (${tags.map(quote).join(',')})`);
+        }
+        const where = conds.length > 0 ? ` WHERE ${conds.join(' AND ')}` : '';
+        if (!rollup) {
+            return `SELECT * FROM ${tableName}${where}`;
+        }
+        const bucket = `ROLLUP('${rollup.unit}', ${rollup.n}, ${timeCol.name})`;
+        return `SELECT ${nameCol.name}, ${bucket} AS ${timeCol.name}, ${agg}(${valueCol.name}) AS ${valueCol.name}`
+            + ` FROM ${tableName}${where}`
+            + ` GROUP BY ${nameCol.name}, ${bucket} ORDER BY ${nameCol.name}, ${bucket}`;
+    };
+
+    if (config.split === 'tag') {
+        const tagNames = tags.length > 0 ? tags : stats.map((s) => s.name);
+        return tagNames.map((tag) => ({
+            output: splitPath(config.output, tag),
+            sqlText: query([tag], from, to),
+            total: estimate(stats.filter((s) => s.name === tag), from, to, rollup),
+        }));
+    }
+    if (config.split === 'day') {
+        let lo = from, hi = to;
+        for (const s of stats) {
+            if (s.count && !from && (!lo || s.min.before(lo))) {
+                lo = s.min;
+            }
+            if (s.count && !to && (!hi || !s.max.before(hi))) {
+                hi = s.max.add(1);
+            }
+        }
+        if (!lo || !hi) {
+            return [];
+        }
+        return pretty.splitDays(lo, hi, config.tz).map((d) => ({
+            output: splitPath(config.output, d.name),
+            sqlText: query(tags, d.from, d.to),
+            total: estimate(stats, d.from, d.to, rollup),
+        }));
+    }
+    return [{ output: config.output, sqlText: query(tags, from, to), total: estimate(stats, from, to, rollup) }];
+}
+
+// tableJobs returns the query to export a table other than a tag table.
+function tableJobs(conn, schema) {
+    if (config.tag || config.rollup || config.split) {
+        throw new Error(`--tag, --rollup and --split require a tag table`);
+    }
+    const conds = logConditions(schema);
+    const where = conds.length > 0 ? ` WHERE ${conds.join(' AND ')}` : '';
+    let total = 0;
+    if (!config.silent) {
+        const result = conn.queryRow(`SELECT COUNT(*) AS count FROM ${tableName}${where}`);
+        total = result.count || 0;
+    }
+    return [{ output: config.output, sqlText: `SELECT * FROM ${tableName}${where}`, total }];
+}
+
+// chunkSource returns the query conditions and the key to export the table in chunks,
+// the rows are ordered by _RID of a log table or the time of a tag table.
+function chunkSource(conn, names, schema) {
+    if (config.rollup || config.split) {
+        throw new Error('--rollup and --split are not allowed with --chunk-rows');
+    }
+    const source = { columns: schema.columns.map((c) => c.name), total: 0 };
+    if (schema.type === machcli.TableType.Tag) {
+        const nameCol = schema.columns.find((c) => c.flag & machcli.ColumnFlag.TagName);
+        const timeCol = schema.columns.find((c) => c.flag & machcli.ColumnFlag.Basetime);
+        const tags = parseTags();
+        const from = config.from ? parseTimeArg('from', config.from) : null;
+        const to = config.to ? parseTimeArg('to', config.to) : null;
+        source.key = timeCol.name;
+        source.time = true;
+        source.conds = rangeConditions(timeCol.name, from, to);
+        if (tags.length > 0) {
+            source.conds.unshift(`${nameCol.name} IN (${tags.map(quote).join(',')})`);
+        }
+        if (!config.silent) {
+            source.total = estimate(queryStats(conn, names, tags), from, to, null);
+        }
+        return source;
+    }
+    if (schema.type !== machcli.TableType.Log) {
+        throw new Error('--chunk-rows requires a log or tag table');
+    }
+    if (config.tag) {
+        throw new Error('--tag requires a tag table');
+    }
+    source.key = '_RID';
+    source.time = false;
+    source.conds = logConditions(schema);
+    if (!config.silent) {
+        const where = source.conds.length > 0 ? ` WHERE ${source.conds.join(' AND ')}` : '';
+        const result = conn.queryRow(`SELECT COUNT(*) AS count FROM ${tableName}${where}`);
+        source.total = result.count || 0;
+    }
+    return source;
+}
+
+// loadManifest returns the manifest of --checkpoint, or a new one if the file does not exist.
+function loadManifest(source) {
+    const manifest = {
+        table: tableName,
+        key: source.key,
+        chunkRows: config.chunkRows,
+        format: String(config.format).toLowerCase(),
+        output: path.resolve(config.output),
+        compress: config.compress,
+        parts: [],
+        done: false,
+    };
+    if (!config.checkpoint) {
+        return manifest;
+    }
+    let content;
+    try {
+        content = fs.readFile(path.resolve(config.checkpoint));
+    } catch {
+        return manifest;
+    }
+    const saved = JSON.parse(content);
+    if (saved.table !== manifest.table || saved.key !== manifest.key) {
+        throw new Error(`checkpoint '${config.checkpoint}' is of the table '${saved.table}' ordered by '${saved.key}'`);
+    }
+    // the parts of the other options can not be continued
+    for (const [name, option] of [['chunkRows', 'chunk-rows'], ['format', 'format'], ['output', 'output'], ['compress', 'compress']]) {
+        if (saved[name] !== manifest[name]) {
+            throw new Error(`checkpoint '${config.checkpoint}' is of --${option} '${saved[name]}', not '${manifest[name]}'`);
+        }
+    }
+    return saved;
+}
+
+function saveManifest(manifest) {
+    if (config.checkpoint) {
+        fs.writeFileSync(path.resolve(config.checkpoint), JSON.stringify(manifest, null, 2));
+    }
+}
+
+// keyString returns the key of a row as it is written in the manifest,
+// the time is in nanoseconds as a string as it does not fit in a javascript number.
+function keyString(v, isTime) {
+    if (isTime) {
+        return `${v.unix()}${String(v.nanosecond()).padStart(9, '0')}`;
+    }
+    return String(v);
+}
+
+// exportChunks writes the rows after the last part of the manifest into the numbered parts of --chunk-rows,
+// a part is recorded in the manifest with its last key when the file is completed.
+// The rows of the same time key are kept in a part, so a part of a tag table can exceed --chunk-rows.
+function exportChunks(conn, source, manifest) {
+    const conds = [...source.conds];
+    const last = manifest.parts.length > 0 ? manifest.parts[manifest.parts.length - 1].last : null;
+    if (last !== null) {
+        conds.push(`${source.key} > ${source.time ? `FROM_TIMESTAMP(${last})` : last}`);
+    }
+    const where = conds.length > 0 ? ` WHERE ${conds.join(' AND ')}` : '';
+    const sqlText = `SELECT ${source.columns.join(', ')}, ${source.key} AS EXPORT_CHUNK_KEY FROM ${tableName}${where} ORDER BY ${source.key}`;
+    const tableConfig = {
+        format: config.format,
+        timeformat: config.timeformat,
+        tz: config.tz,
+        precision: config.precision,
+        nullValue: config.nullValue,
+        header: config.header,
+        rownum: false,
+        footer: false,
+        pause: false,
+        table: tableName,
+    };
+
+    let tracker = null;
+    if (!config.silent) {
+        tracker = pretty.Progress({ showPercentage: source.total > 0 }).tracker({
+            message: `Exporting ${tableName}`,
+            total: source.total,
+        });
+        tracker.setValue(manifest.parts.reduce((sum, p) => sum + p.rows, 0));
+    }
+    let rows, part = null;
+    let lastKey = null;
+    let nRows = 0;
+    try {
+        rows = conn.query(sqlText);
+        const n = rows.columnNames.length - 1;
+        for (const row of rows) {
+            const values = [...row];
+            const key = keyString(values[n], source.time);
+            if (part && part.rows >= config.chunkRows && key !== lastKey) {
+                commitPart(manifest, part, lastKey);
+                part = null;
+            }
+            if (!part) {
+                const no = manifest.parts.length + 1;
+                const output = splitPath(config.output, `part${String(no).padStart(4, '0')}`);
+                const box = pretty.Table(tableConfig);
+                part = { no, box, rows: 0, output: pretty.openOutput(box, { ...config, output }) };
+                if (no === 1 && source.preamble) {
+                    box.setPreamble(source.preamble);
+                }
+                box.appendHeader(rows.columnNames.slice(0, n));
+                box.setColumnTypes(rows.columnTypes.slice(0, n));
+            }
+            part.box.append(values.slice(0, n));
+            if (part.box.requirePageRender()) {
+                part.box.render();
+            }
+            part.rows++;
+            lastKey = key;
+            nRows++;
+            tracker && tracker.increment(1);
+        }
+        if (part) {
+            commitPart(manifest, part, lastKey);
+            part = null;
+        }
+        manifest.done = true;
+        saveManifest(manifest);
+        tracker && tracker.markAsDone();
+    } catch (err) {
+        tracker && tracker.markAsErrored();
+        if (part) {
+            part.box.abort();
+            part.output.close();
+        }
+        throw err;
+    } finally {
+        rows && rows.close();
+    }
+    return nRows;
+}
+
+function commitPart(manifest, part, lastKey) {
+    part.box.close();
+    part.output.close();
+    manifest.parts.push({ part: part.no, path: part.output.path, rows: part.rows, last: lastKey });
+    saveManifest(manifest);
+}
+
+// runChunks exports the table into the parts of --chunk-rows.
+function runChunks() {
+    if (config.output === '' || config.output === '-') {
+        console.println('--chunk-rows requires --output');
+        process.exit(1);
+    }
+    let db, conn, manifest, stop;
+    let exitCode = 0;
     try {
         db = new machcli.Client(config);
+        stop = db.cancelOnInterrupt();
         conn = db.connect();
-        const result = conn.queryRow(`SELECT COUNT(*) AS count FROM ${tableName}`);
-        const rowCount = result.count || 0;
-        args.push('--progress', rowCount);
+        const names = db.normalizeTableName(tableName);
+        const schema = machcli.queryTableSchema(conn, names);
+        if (!schema) {
+            throw new Error(`Table '${tableName}' not found`);
+        }
+        const source = chunkSource(conn, names, schema);
+        if (config.createTable) {
+            source.preamble = machcli.createTableSQL(tableName, schema);
+        }
+        manifest = loadManifest(source);
+        if (manifest.done) {
+            console.println(`Export of ${tableName} in '${config.checkpoint}' is already completed`);
+        } else {
+            const resumed = manifest.parts.length;
+            const nRows = exportChunks(conn, source, manifest);
+            setTimeout(() => {
+                console.println(`Export ${pretty.Ints(nRows)} rows into ${manifest.parts.length - resumed} parts completed.`);
+            }, 100);
+        }
     } catch (err) {
-        console.println(`Failed to get row count: ${err.message}`);
-        process.exit(1);
+        console.println(db && db.cancelled() ? 'query cancelled' : `Error: ${err.message}`);
+        if (manifest && config.checkpoint) {
+            console.println(`Run again with --checkpoint ${config.checkpoint} to continue from part ${manifest.parts.length + 1}.`);
+        }
+        exitCode = 1;
     } finally {
+        stop && stop();
         conn && conn.close();
         db && db.close();
     }
+    if (exitCode !== 0) {
+        process.exit(exitCode);
+    }
 }
 
-args.push(`SELECT * FROM ${tableName}`);
+// runJobs exports the table by the sql command for each output file.
+function runJobs() {
+    if (config.checkpoint) {
+        console.println('--checkpoint requires --chunk-rows');
+        process.exit(1);
+    }
+    if (config.split && config.split !== 'tag' && config.split !== 'day') {
+        console.println(`Invalid --split '${config.split}', it should be tag or day`);
+        process.exit(1);
+    }
+    if (config.split && (config.output === '' || config.output === '-')) {
+        console.println('--split requires --output');
+        process.exit(1);
+    }
+
+    let jobs = [];
+    let db, conn;
+    try {
+        db = new machcli.Client(config);
+        conn = db.connect();
+        const names = db.normalizeTableName(tableName);
+        const schema = machcli.queryTableSchema(conn, names);
+        if (!schema) {
+            throw new Error(`Table '${tableName}' not found`);
+        }
+        if (schema.type === machcli.TableType.Tag) {
+            jobs = tagJobs(conn, names, schema);
+        } else {
+            jobs = tableJobs(conn, schema);
+        }
+    } catch (err) {
+        console.println(`Error: ${err.message}`);
+        db && db.close();
+        process.exit(1);
+    } finally {
+        conn && conn.close();
+    }
+
+    let args = [
+        '--compress', config.compress,
+        '--format', config.format,
+        '--timeformat', config.timeformat,
+        '--tz', config.tz,
+        '--precision', config.precision,
+        '--null-value', config.nullValue,
+        '--no-rownum',
+        '--no-pause',
+        '--no-footer',
+        '--table', tableName,
+    ];
+    if (config.createTable) {
+        args.push('--create-table');
+    }
 
-process.exec('sql', ...args);
\ No newline at end of file
+    if (config.header) {
+        args.push('--header');
+    } else {
+        args.push('--no-header');
+    }
+
+    // Ctrl-C cancels the running sql command and the remaining jobs
+    const stop = db.cancelOnInterrupt();
+    try {
+        for (const job of jobs) {
+            const progress = config.silent ? -1 : job.total;
+            process.exec('sql', ...args, '--output', job.output, '--progress', progress, job.sqlText);
+            if (db.cancelled()) {
+                console.println('query cancelled');
+                break;
+            }
+        }
+    } finally {
+        stop();
+        db.close();
+    }
+}
+
+if (config.chunkRows > 0) {
+    runChunks();
+} else {
+    runJobs();
+}
@@ -5,19 +5,21 @@ const parseArgs = require('util/parseArgs');
 const machcli = require('/usr/lib/machcli');
 const pretty = require('/usr/lib/pretty');
 const fs = require('fs');
-const parser = require('parser');
-const zlib = require('zlib');
 
 const options = {
     help: { type: 'boolean', short: 'h', description: 'Show this help message', default: false },
     input: { type: 'string', short: 'i', description: "input file (default:'-' stdin)", default: '-' },
-    compress: { type: 'string', description: "compression type (none, gzip)", default: 'none' },
-    format: { type: 'string', short: 'f', description: "input format (csv, tsv, ndjson)", default: 'csv' },
+    compress: { type: 'string', description: "compression of the input (auto, none, gzip, zstd, bzip2)", default: 'auto' },
+    format: { type: 'string', short: 'f', description: "input format (csv, tsv, ndjson, parquet, arrow)", default: 'csv' },
     timeformat: { type: 'string', short: 't', description: "time format [ns|us|ms|s|<timeformat>]", default: 'ns' },
     tz: { type: 'string', description: "time zone for handling datetime (default: time zone)", default: 'local' },
     header: { type: 'string', description: "header option [skip|columns|none]", default: 'none' },
     nullValue: { type: 'string', description: "string to represent null values", default: 'NULL' },
     dryRun: { type: 'boolean', description: "run in dry mode", default: false },
+    rejectFile: { type: 'string', description: "file to write the rejected lines with the line numbers and errors as ndjson", default: '' },
+    maxErrors: { type: 'integer', description: "maximum number of rejected lines before stopping (-1: unlimited with --reject-file, otherwise 0)", default: -1 },
+    parallel: { type: 'integer', description: "number of workers that import csv or tsv with their own connections", default: 0 },
+    resumeFromLine: { type: 'integer', description: "line number of the input to resume from, the lines before it are skipped", default: 0 },
 }
 
 const positionals = [
@@ -54,123 +56,319 @@ if (showHelp || (!tableName) || tableName.length === 0) {
 const db = new machcli.Client(config);
 const conn = db.connect();
 let appender = conn.append(tableName);
-const colDefs = appender.columns();
 
-let columnNames = [];
-let columnTypes = [];
-for (let i = 0; i < colDefs.length; i++) {
-    let col = colDefs[i];
-    // skip system columns like _RID
-    if (col.Name === '_RID') continue;
-    columnNames.push(col.name);
-    columnTypes.push(col.type.toString());
+if (config.format === 'parquet' || config.format === 'arrow') {
+    importRecords();
+} else if (config.format === 'ndjson') {
+    importNDJSON();
+} else if (config.parallel > 1) {
+    importParallel();
+} else {
+    importText();
 }
 
-const csvParser = parser.csv({
-    separator: config.format === 'tsv' ? '\t' : ',',
-})
-
-
-switch (config.header) {
-    case 'columns':
-        csvParser.headers = true;
-        break;
-    case 'skip':
-        csvParser.headers = true;
-        break;
-    case 'none':
-        csvParser.headers = columnNames;
-        break;
-    default:
-        throw new Error(`Invalid header option: ${config.header}`);
+// importRecords imports a parquet or arrow IPC file,
+// the values keep their types, e.g. timestamps are converted without formatting.
+function importRecords() {
+    if (config.input === '' || config.input === '-') {
+        console.println(`Error: ${config.format} format requires --input`);
+        conn.close();
+        db.close();
+        process.exit(1);
+    }
+    const path = require('path');
+    const inputPath = path.resolve(config.input);
+    let reader = null;
+    let tracker = null;
+    let failed = false;
+    try {
+        reader = machcli.openRecordReader(config.format, inputPath);
+        // all the mismatched columns are reported before appending any row
+        reader.bind(appender);
+        const total = reader.numRows();
+        tracker = pretty.Progress({ showPercentage: total > 0 }).tracker({
+            label: `Importing ${config.input} into ${tableName}`,
+            total: total > 0 ? total : 0,
+        });
+        let nRows = 0;
+        if (config.dryRun) {
+            // arrow IPC has no number of rows until it is read
+            nRows = total >= 0 ? total : reader.count();
+        } else {
+            let n = 0;
+            while ((n = reader.append(1000)) > 0) {
+                nRows += n;
+                tracker.increment(n);
+            }
+        }
+        let result = appender.close();
+        tracker.markAsDone();
+        setTimeout(() => {
+            if (config.dryRun) {
+                console.println(`Import ${pretty.Ints(nRows)} rows dry run completed.`);
+            } else {
+                console.println(`Import ${pretty.Ints(nRows)} rows completed. ${result}`);
+            }
+        }, 100);
+    } catch (err) {
+        tracker && tracker.markAsErrored();
+        console.println(`Error during import: ${err.message}`);
+        failed = true;
+    } finally {
+        reader && reader.close();
+        conn.close();
+        db.close();
+    }
+    if (failed) {
+        process.exit(1);
+    }
 }
 
-let nRows = 0;
-let totalLines = fs.countLines(config.input);
+// importNDJSON imports the objects of NDJSON lines, the keys are matched to the columns.
+function importNDJSON() {
+    importLines((input) => machcli.openNDJSONReader(input, {
+        timeformat: config.timeformat,
+        tz: config.tz,
+        compress: config.compress,
+        dryRun: config.dryRun,
+        resumeFromLine: config.resumeFromLine,
+    }));
+}
 
-const tracker = pretty.Progress({ showPercentage: true }).tracker({
-    label: `Importing ${config.input} into ${tableName}`,
-    total: totalLines,
-})
+// importText imports the records of csv or tsv, the fields are converted to the types of the columns.
+function importText() {
+    importLines((input) => machcli.openCSVReader(input, {
+        separator: config.format === 'tsv' ? '\t' : ',',
+        header: config.header,
+        timeformat: config.timeformat,
+        tz: config.tz,
+        nullValue: config.nullValue,
+        compress: config.compress,
+        dryRun: config.dryRun,
+        resumeFromLine: config.resumeFromLine,
+    }));
+}
 
-const onHeader = (headers) => {
-    tracker.increment(1);
-    if (config.header !== 'columns') {
-        // skip header row, do nothing
-        return;
-    }
-    columnNames = [];
-    columnTypes = [];
-    for (let i = 0; i < headers.length; i++) {
-        let found = -1;
-        for (let j = 0; j < colDefs.length; j++) {
-            if (headers[i].toUpperCase() === colDefs[j].Name) {
-                found = j;
+// importLines imports the lines of the input by the reader that open() returns.
+// The rejected lines are written to the reject file with their errors,
+// the import stops when the rejected lines exceed --max-errors.
+// The progress is the bytes read from the input, the compressed bytes if it is compressed.
+function importLines(open) {
+    const path = require('path');
+    const fromStdin = config.input === '' || config.input === '-';
+    let reader = null;
+    let rejects = null;
+    let tracker = null;
+    let failed = false;
+    let nRows = 0;
+    let nSkipped = 0;
+    let nextLine = 0;
+    try {
+        reader = open(fromStdin ? '-' : path.resolve(config.input));
+        reader.bind(appender);
+        rejects = openRejects();
+        const size = reader.size();
+        tracker = pretty.Progress({ showPercentage: size > 0 }).tracker({
+            label: `Importing ${config.input} into ${tableName}`,
+            total: size > 0 ? size : 0,
+        });
+        while (true) {
+            const result = reader.append(1000);
+            tracker.setValue(result.offset);
+            if (result.lines === 0) {
                 break;
             }
+            nextLine = result.nextLine;
+            nRows += result.rows;
+            nSkipped += result.skipped;
+            // write all the rejected lines of the batch before stopping
+            let fatal = null;
+            for (const rej of result.rejects) {
+                try {
+                    rejects.add(rej.line, rej.error, rej.input);
+                } catch (err) {
+                    fatal = fatal || err;
+                }
+            }
+            if (fatal) {
+                throw fatal;
+            }
         }
-        if (found === -1) {
-            throw new Error(`Column '${headers[i]}' not found in table '${tableName}'`);
-        } else {
-            columnNames.push(colDefs[found].name);
-            columnTypes.push(colDefs[found].type.toString());
+        rejects.close();
+        let result = appender.close();
+        tracker.markAsDone();
+        setTimeout(() => {
+            if (config.dryRun) {
+                console.println(`${summary(nRows, rejects, nSkipped)} dry run completed.`);
+            } else {
+                console.println(`${summary(nRows, rejects, nSkipped)} completed. ${result}`);
+            }
+        }, 100);
+    } catch (err) {
+        tracker && tracker.markAsErrored();
+        console.println(`Error during import: ${err.message}`);
+        if (rejects) {
+            // the lines of the batch are processed, keep the rows imported so far
+            rejects.close();
+            let result = config.dryRun ? '' : appender.close();
+            // the line numbers are of the file, the header and the lines of a quoted field are counted
+            console.println(`${summary(nRows, rejects, nSkipped)} stopped at line ${Math.max(0, nextLine - 1)}. ${result}`);
+            if (!config.dryRun && nextLine > 1) {
+                console.println(`Resume with --resume-from-line ${nextLine}`);
+            }
         }
+        failed = true;
+    } finally {
+        reader && reader.close();
+        conn.close();
+        db.close();
+    }
+    if (failed) {
+        process.exit(1);
     }
 }
 
-appender = appender.withInputColumns(...columnNames);
-
-fs.createReadStream(config.input, { highWaterMark: 1024 })
-    .pipe(csvParser)
-    .on('headers', onHeader)
-    .on('data', (row) => {
-        nRows++;
-        tracker.increment(1);
-        let rec = [];
-        for (let i = 0; i < columnNames.length; i++) {
-            let colName = columnNames[i];
-            let colType = columnTypes[i];
-            let value = row[colName];
-            switch (colType) {
-                case 'datetime':
-                    value = pretty.parseTime(value, config.timeformat, config.tz);
-                    break;
-                case "double":
-                    value = parseFloat(value);
-                    break;
-            }
-            if (value === config.nullValue) {
-                rec.push(null);
-                continue;
-            } else {
-                rec.push(value);
+// importParallel imports csv or tsv files by the workers that have their own connections and appenders.
+// The input is a file or a glob pattern, a file is split into the ranges for the workers
+// at line boundaries, so the records must not span lines.
+function importParallel() {
+    if (config.input === '' || config.input === '-') {
+        console.println('Error: --parallel requires --input');
+        conn.close();
+        db.close();
+        process.exit(1);
+    }
+    if (config.resumeFromLine > 0) {
+        console.println('Error: --resume-from-line can not be used with --parallel');
+        conn.close();
+        db.close();
+        process.exit(1);
+    }
+    const path = require('path');
+    let job = null;
+    let rejects = null;
+    let tracker = null;
+    let failed = false;
+    let nRows = 0;
+    try {
+        // the workers have their own appenders
+        appender.close();
+        rejects = openRejects();
+        job = db.importParallel(tableName, [path.resolve(config.input)], {
+            workers: config.parallel,
+            separator: config.format === 'tsv' ? '\t' : ',',
+            header: config.header,
+            timeformat: config.timeformat,
+            tz: config.tz,
+            nullValue: config.nullValue,
+            compress: config.compress,
+            dryRun: config.dryRun,
+        });
+        // the progress is the bytes read from the files, the compressed bytes if they are compressed
+        tracker = pretty.Progress({ showPercentage: true }).tracker({
+            label: `Importing ${config.input} into ${tableName} with ${config.parallel} workers`,
+            total: job.total(),
+        });
+        let fatal = null;
+        let status = null;
+        do {
+            status = job.wait(250);
+            tracker.setValue(status.offset);
+            nRows = status.rows;
+            for (const rej of status.rejects) {
+                try {
+                    rejects.add(rej.line, rej.error, rej.input, rej.path);
+                } catch (err) {
+                    if (!fatal) {
+                        fatal = err;
+                        job.cancel();
+                    }
+                }
             }
+        } while (!status.done);
+        rejects.close();
+        if (status.error) {
+            fatal = new Error(status.error);
         }
-        if (!config.dryRun) {
-            appender.append(...rec);
+        if (fatal) {
+            throw fatal;
         }
-    })
-    .on('error', (err) => {
+        tracker.markAsDone();
+    } catch (err) {
+        tracker && tracker.markAsErrored();
         console.println(`Error during import: ${err.message}`);
-        tracker.markAsErrored();
-        conn && conn.close();
-        db && db.close();
+        failed = true;
+    } finally {
+        rejects && rejects.close();
+        conn.close();
+    }
+    db.close();
+    if (!job) {
         process.exit(1);
-    })
-    .on('end', () => {
-        let result = appender.close();
-        if (tracker.value() < totalLines) {
-            tracker.setValue(totalLines);
+    }
+    // the results of appender.close() of the workers
+    const results = job.results();
+    const report = () => {
+        let msg = summary(nRows, rejects, 0);
+        console.println(config.dryRun ? `${msg} dry run completed.` : `${msg} ${failed ? 'stopped' : 'completed'}.`);
+        for (const r of results) {
+            let line = `  worker ${r.worker}: success ${pretty.Ints(r.success)}, fail ${pretty.Ints(r.fail)}`;
+            if (r.error) {
+                line += `, error: ${r.error}`;
+            }
+            console.println(line);
         }
-        tracker.markAsDone();
-        setTimeout(() => {
-            if (config.dryRun) {
-                console.println(`Import ${pretty.Ints(nRows)} rows dry run completed.`);
-            } else {
-                console.println(`Import ${pretty.Ints(nRows)} rows completed. ${result}`);
+    }
+    if (failed) {
+        report();
+        process.exit(1);
+    }
+    setTimeout(report, 100);
+}
+
+// openRejects returns the collector of the rejected lines.
+// The lines are written to --reject-file with their line numbers and errors,
+// add() throws if the number of the rejected lines exceeds --max-errors.
+function openRejects() {
+    let maxErrors = config.maxErrors;
+    if (maxErrors < 0) {
+        maxErrors = config.rejectFile ? Infinity : 0;
+    }
+    let writer = null;
+    if (config.rejectFile) {
+        const path = require('path');
+        writer = fs.createWriteStream(path.resolve(config.rejectFile), { encoding: 'utf8' });
+    }
+    return {
+        count: 0,
+        add(line, error, input, path) {
+            this.count++;
+            if (writer) {
+                const rej = path ? { path: path, line: line, error: error, input: input } : { line: line, error: error, input: input };
+                writer.write(JSON.stringify(rej) + '\n');
             }
-        }, 100);
-        conn && conn.close();
-        db && db.close();
-    });
+            if (this.count > maxErrors) {
+                if (maxErrors === 0) {
+                    throw new Error(`line ${line}: ${error}`);
+                }
+                throw new Error(`too many rejected lines, exceeds --max-errors ${maxErrors}, line ${line}: ${error}`);
+            }
+        },
+        close() {
+            writer && writer.end();
+            writer = null;
+        },
+    };
+}
 
+// summary returns the numbers of the accepted, rejected and skipped lines.
+function summary(nRows, rejects, nSkipped) {
+    let msg = `Import ${pretty.Ints(nRows)} rows`;
+    if (rejects.count > 0 || nSkipped > 0) {
+        msg += `, ${pretty.Ints(rejects.count)} rejected, ${pretty.Ints(nSkipped)} skipped`;
+    }
+    if (rejects.count > 0 && config.rejectFile) {
+        msg += ` (rejects in ${config.rejectFile})`;
+    }
+    return msg;
+}
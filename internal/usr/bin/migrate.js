@@ -0,0 +1,259 @@
+'use strict';
+
+const process = require('process');
+const path = require('path');
+const machcli = require('/usr/lib/machcli');
+const neoapi = require('/usr/lib/neoapi');
+const pretty = require('/usr/lib/pretty');
+const { parseAndRun } = require('/usr/lib/opts');
+
+const optionHelp = { type: 'boolean', short: 'h', description: 'Show this help message', default: false }
+const optionDir = { type: 'string', short: 'd', description: "directory of the V<n>__<name>.sql files", default: '.' }
+const optionTable = { type: 'string', description: "table of the applied versions", default: 'MIGRATION_HISTORY' }
+
+const defaultConfig = {
+    usage: 'Usage: migrate <command> [options]',
+    options: {
+        help: optionHelp,
+    }
+};
+
+const statusConfig = {
+    func: doStatus,
+    command: 'status',
+    usage: 'migrate status [options]',
+    description: 'Show the applied and pending migrations',
+    options: {
+        help: optionHelp,
+        dir: optionDir,
+        table: optionTable,
+        ...pretty.TableArgOptions,
+    }
+}
+
+const upConfig = {
+    func: doUp,
+    command: 'up',
+    usage: 'migrate up [options]',
+    description: 'Apply the pending migrations in the order of the versions',
+    options: {
+        help: optionHelp,
+        dir: optionDir,
+        table: optionTable,
+        to: { type: 'integer', description: "apply the migrations up to the version (0: all)", default: 0 },
+        dryRun: { type: 'boolean', description: "print the pending migrations without applying", default: false },
+        outOfOrder: { type: 'boolean', description: "apply the pending migrations below the latest applied version", default: false },
+    }
+}
+
+const verifyConfig = {
+    func: doVerify,
+    command: 'verify',
+    usage: 'migrate verify [options]',
+    description: 'Check that the files of the applied migrations are not edited',
+    options: {
+        help: optionHelp,
+        dir: optionDir,
+        table: optionTable,
+    }
+}
+
+const commands = [
+    statusConfig,
+    upConfig,
+    verifyConfig,
+];
+
+parseAndRun(process.argv.slice(2), defaultConfig, commands);
+
+// openState returns the migration files of --dir and the applied versions of --table,
+// the table that does not exist has no applied versions.
+function openState(config) {
+    const state = { db: null, conn: null, files: [], applied: new Map(), hasTable: false };
+    state.files = [...machcli.scanMigrations(path.resolve(config.dir))];
+    state.db = new machcli.Client(config);
+    state.conn = state.db.connect();
+    state.hasTable = machcli.queryTableSchema(state.conn, state.db.normalizeTableName(config.table)) !== null;
+    if (state.hasTable) {
+        let rows;
+        try {
+            rows = state.conn.query(`SELECT VERSION, NAME, CHECKSUM, APPLIED_AT FROM ${config.table} ORDER BY VERSION`);
+            for (const row of rows) {
+                state.applied.set(Number(row.VERSION), { name: row.NAME, checksum: row.CHECKSUM, appliedAt: row.APPLIED_AT });
+            }
+        } finally {
+            rows && rows.close();
+        }
+    }
+    return state;
+}
+
+function closeState(state) {
+    state.conn && state.conn.close();
+    state.db && state.db.close();
+}
+
+// migrationStatus returns the status of each version of the files and the applied versions,
+// applied, changed (the file is edited after it is applied), pending, out-of-order (pending
+// below the latest applied version) or missing (the file is removed).
+function migrationStatus(state) {
+    const ret = [];
+    const latest = Math.max(0, ...state.applied.keys());
+    for (const m of state.files) {
+        const version = Number(m.version);
+        const applied = state.applied.get(version);
+        let status = version < latest ? 'out-of-order' : 'pending';
+        if (applied) {
+            status = applied.checksum === m.checksum ? 'applied' : 'changed';
+        }
+        ret.push({ version, name: m.name, status, appliedAt: applied ? applied.appliedAt : null, file: m });
+    }
+    for (const [version, applied] of state.applied) {
+        if (!state.files.some(m => Number(m.version) === version)) {
+            ret.push({ version, name: applied.name, status: 'missing', appliedAt: applied.appliedAt, file: null });
+        }
+    }
+    return ret.sort((a, b) => a.version - b.version);
+}
+
+function doStatus(config, args) {
+    let state;
+    try {
+        state = openState(config);
+        const box = pretty.Table(config);
+        box.appendHeader(['VERSION', 'NAME', 'STATUS', 'APPLIED_AT']);
+        for (const s of migrationStatus(state)) {
+            box.append([s.version, s.name, s.status, s.appliedAt]);
+        }
+        console.println(box.render());
+    } catch (err) {
+        console.println('Error:', err.message);
+        process.exit(1);
+    } finally {
+        state && closeState(state);
+    }
+}
+
+function doVerify(config, args) {
+    let state;
+    let failed = 0;
+    try {
+        state = openState(config);
+        for (const s of migrationStatus(state)) {
+            if (s.status === 'changed') {
+                console.println(`V${s.version} ${s.name}: ${path.basename(s.file.path)} is edited after it was applied`);
+                failed++;
+            } else if (s.status === 'missing') {
+                console.println(`V${s.version} ${s.name}: the file of the applied migration is missing`);
+                failed++;
+            }
+        }
+        if (failed === 0) {
+            console.println(`${state.applied.size} applied migrations verified.`);
+        }
+    } catch (err) {
+        console.println('Error:', err.message);
+        failed++;
+    } finally {
+        state && closeState(state);
+    }
+    if (failed > 0) {
+        process.exit(1);
+    }
+}
+
+function doUp(config, args) {
+    let state;
+    let pending = [];
+    try {
+        state = openState(config);
+        const status = migrationStatus(state);
+        const changed = status.filter(s => s.status === 'changed');
+        if (changed.length > 0) {
+            throw new Error(`V${changed[0].version} ${changed[0].name} is edited after it was applied, see 'migrate verify'`);
+        }
+        // the versions added below the latest applied one would be applied after it
+        const outOfOrder = status.filter(s => s.status === 'out-of-order' && (config.to <= 0 || s.version <= config.to));
+        if (outOfOrder.length > 0 && !config.outOfOrder) {
+            const latest = Math.max(0, ...state.applied.keys());
+            throw new Error(`pending ${outOfOrder.map(s => `V${s.version}`).join(', ')} below the latest applied V${latest}, run with --out-of-order to apply them`);
+        }
+        pending = status.filter(s => (s.status === 'pending' || s.status === 'out-of-order') && (config.to <= 0 || s.version <= config.to));
+    } catch (err) {
+        console.println('Error:', err.message);
+        state && closeState(state);
+        process.exit(1);
+    }
+    if (pending.length === 0) {
+        const current = Math.max(0, ...state.applied.keys());
+        console.println(`No pending migrations, the schema is at version ${current}.`);
+        closeState(state);
+        return;
+    }
+
+    const client = new neoapi.Client(config);
+    Promise.all(pending.map(s => client.splitSqlStatements(s.file.text)))
+        .then((splits) => {
+            let failed = false;
+            try {
+                if (config.dryRun) {
+                    for (let i = 0; i < pending.length; i++) {
+                        const stmts = (splits[i] || []).filter(stmt => stmt && !stmt.isComment);
+                        console.println(`V${pending[i].version} ${pending[i].name}: ${stmts.length} statements`);
+                    }
+                    return;
+                }
+                if (!state.hasTable) {
+                    state.conn.exec(`CREATE TABLE ${config.table} (VERSION long, NAME varchar(200), CHECKSUM varchar(64), APPLIED_AT datetime)`);
+                }
+                for (let i = 0; i < pending.length; i++) {
+                    if (!applyMigration(state, config, pending[i], splits[i] || [])) {
+                        failed = true;
+                        break;
+                    }
+                }
+            } catch (err) {
+                console.println('Error:', err.message);
+                failed = true;
+            } finally {
+                closeState(state);
+            }
+            if (failed) {
+                process.exit(1);
+            }
+        })
+        .catch((err) => {
+            console.println(`Error connecting to server: ${err.message}`);
+            closeState(state);
+            process.exit(1);
+        });
+}
+
+// applyMigration executes the statements of the migration and records the version,
+// it returns false if a statement failed, the statements before it are not rolled back.
+function applyMigration(state, config, s, statements) {
+    const tick = process.now();
+    let count = 0;
+    for (const stmt of statements) {
+        if (!stmt || stmt.isComment) {
+            continue;
+        }
+        try {
+            if (stmt.env && stmt.env.error) {
+                throw new Error(stmt.env.error);
+            }
+            state.conn.exec(stmt.text);
+            count++;
+        } catch (err) {
+            console.println(`Error: ${path.basename(s.file.path)} line ${stmt.beginLine}~${stmt.endLine}: ${err.message}`);
+            if (count > 0) {
+                console.println(`The ${count} statements before it are applied, V${s.version} is not recorded.`);
+            }
+            return false;
+        }
+    }
+    state.conn.exec(`INSERT INTO ${config.table} (VERSION, NAME, CHECKSUM, APPLIED_AT) VALUES (?, ?, ?, ?)`,
+        s.version, s.name, s.file.checksum, process.now());
+    console.println(`Applied V${s.version} ${s.name}, ${count} statements in ${pretty.Durations(process.now().unixNano() - tick.unixNano())}.`);
+    return true;
+}
@@ -3,6 +3,8 @@
 const { ReadLine } = require('readline');
 const process = require('process');
 const { splitFields } = require('util')
+const session = require('@jsh/session');
+const profile = require('@jsh/profile');
 const env = process.env;
 
 const actor = {};
@@ -19,8 +21,124 @@ if (!actor.password) {
     }
 }
 
+actor.session = session.DEFAULT_NAME;
+
 actor.prompt = (lineno) => {
-    return lineno == 0 ? "\x1b[33m" + `${actor.user}` + " \x1b[31mmachbase-neo»\x1b[0m " : "\x1b[31m>\x1b[0m  ";
+    let who = actor.user;
+    if (actor.session !== session.DEFAULT_NAME) {
+        who = `${actor.user}@${actor.session}`;
+    }
+    return lineno == 0 ? "\x1b[33m" + who + " \x1b[31mmachbase-neo»\x1b[0m " : "\x1b[31m>\x1b[0m  ";
+};
+
+// tlsEnv returns the TLS options of the environment variables, e.g. NEOSHELL_TLS.
+function tlsEnv(get) {
+    const ret = {};
+    for (const k of session.TLS_ENV) {
+        const v = get(k);
+        if (v !== undefined && v !== null && v !== '') {
+            ret[k] = String(v);
+        }
+    }
+    return ret;
+}
+
+// credentials of the sessions opened in this shell,
+// child processes follow the active session through the environment variables.
+const credentials = {
+    [session.DEFAULT_NAME]: {
+        host: env.get('NEOSHELL_HOST'),
+        user: env.get('NEOSHELL_USER'),
+        password: env.get('NEOSHELL_PASSWORD'),
+        tls: tlsEnv((k) => env.get(k)),
+    },
+};
+
+// \use [name] switches the active session,
+// a session that is not opened yet is opened with the profile of the same name.
+actor.use = (args) => {
+    if (args.length == 0) {
+        for (const s of session.list()) {
+            console.println(`${s.active ? '*' : ' '} ${s.name}\t${s.user}@${s.server}`);
+        }
+        return;
+    }
+    const name = args[0];
+    let cred = credentials[name];
+    if (!cred) {
+        const p = profile.load(name);
+        cred = {
+            host: p.host || credentials[session.DEFAULT_NAME].host,
+            user: p.user || 'sys',
+            password: p.password || 'manager',
+            tls: tlsEnv((k) => p.env[k]),
+        };
+        session.open(name, { server: cred.host, user: cred.user, password: cred.password, env: cred.tls });
+        credentials[name] = cred;
+    }
+    session.use(name);
+    env.set('NEOSHELL_HOST', cred.host);
+    env.set('NEOSHELL_USER', cred.user);
+    env.set('NEOSHELL_PASSWORD', cred.password);
+    // the TLS options of the previous session are cleared
+    for (const k of session.TLS_ENV) {
+        env.set(k, cred.tls[k] || '');
+    }
+    actor.user = cred.user;
+    actor.password = cred.password;
+    actor.session = name;
+};
+
+// variables of \set, the :name placeholders of the SQL statements are bound to them.
+actor.vars = {};
+
+// \set [name [value]] sets the variable, or lists the variables without arguments.
+// The value is the rest of the line, a quoted value is bound as a string.
+actor.set = (line, args) => {
+    if (args.length == 0) {
+        for (const name of Object.keys(actor.vars).sort()) {
+            console.println(`${name} = ${actor.vars[name]}`);
+        }
+        return;
+    }
+    const name = args[0];
+    if (!/^[A-Za-z_][A-Za-z0-9_]*$/.test(name)) {
+        throw new Error(`invalid variable name '${name}'`);
+    }
+    actor.vars[name] = line.replace(/^\S+\s+\S+\s*/, '');
+};
+
+// \unset name removes the variable.
+actor.unset = (args) => {
+    for (const name of args) {
+        delete actor.vars[name];
+    }
+};
+
+// \timeout [duration] sets the timeout of the following statements, e.g. 30s,
+// '0' or 'off' removes it. It shows the current timeout without arguments.
+actor.timeout = (args) => {
+    if (args.length == 0) {
+        const timeout = env.get('NEOSHELL_QUERY_TIMEOUT');
+        console.println(timeout ? `timeout ${timeout}` : 'no timeout');
+        return;
+    }
+    let timeout = args[0];
+    if (timeout === 'off' || timeout === '0') {
+        timeout = '';
+    } else if (!/^(\d+(\.\d+)?(ns|us|ms|s|m|h))+$/.test(timeout)) {
+        throw new Error(`invalid timeout '${timeout}', e.g. 30s, 1m30s`);
+    }
+    env.set('NEOSHELL_QUERY_TIMEOUT', timeout);
+};
+
+// paramArgs returns the --param arguments of the variables for sql.js.
+actor.paramArgs = () => {
+    const args = [];
+    for (const name of Object.keys(actor.vars)) {
+        args.push('--param', `${name}=${actor.vars[name]}`);
+    }
+    return args;
 };
 
 const SQL_VERBS = new Set([
@@ -71,7 +189,7 @@ actor.process = (line) => {
 
         // Handle SQL commands
         if (SQL_VERBS.has(firstField.toUpperCase())) {
-            process.exec("sql.js", line);
+            process.exec("sql.js", ...actor.paramArgs(), line);
             return;
         }
 
@@ -82,10 +200,34 @@ actor.process = (line) => {
             return;
         }
 
+        if (firstField === '\\use') {
+            actor.use(fields.slice(1));
+            return;
+        }
+
+        if (firstField === '\\set') {
+            actor.set(line, fields.slice(1));
+            return;
+        }
+
+        if (firstField === '\\unset') {
+            actor.unset(fields.slice(1));
+            return;
+        }
+
+        if (firstField === '\\timeout') {
+            actor.timeout(fields.slice(1));
+            return;
+        }
+
         if (firstField.startsWith('\\')) {
             // Execute js command (backslash prefix without semicolon)
             const command = firstField.substring(1);
             const args = fields.slice(1);
+            if (command === 'run') {
+                // the variables of \set are bound to the :name placeholders of the script
+                args.unshift(...actor.paramArgs());
+            }
             process.exec(command, ...args);
             return;
         }
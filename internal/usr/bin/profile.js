@@ -0,0 +1,126 @@
+'use strict';
+
+const process = require('process');
+const profile = require('@jsh/profile');
+const pretty = require('/usr/lib/pretty');
+const { parseAndRun } = require('/usr/lib/opts');
+const env = process.env;
+
+const optionHelp = { type: 'boolean', short: 'h', description: 'Show this help message', default: false }
+
+const defaultConfig = {
+    usage: 'Usage: profile <command> [options]',
+    options: {
+        help: optionHelp,
+    }
+};
+
+const listConfig = {
+    func: doList,
+    command: 'list',
+    usage: 'profile list',
+    description: 'List all saved connection profiles',
+    options: {
+        help: optionHelp,
+        ...pretty.TableArgOptions,
+    }
+}
+
+const saveConfig = {
+    func: doSave,
+    command: 'save',
+    usage: 'profile save [options] <name>',
+    description: 'Save a connection profile, the current connection is used for the omitted options',
+    options: {
+        help: optionHelp,
+        host: { type: 'string', description: 'machbase-neo host (host:port)', default: '' },
+        user: { type: 'string', description: 'user name', default: '' },
+        password: { type: 'string', description: 'password', default: '' },
+        volumes: { type: 'string', short: 'v', description: 'comma separated volumes to mount (format: /mountpoint=source)', default: '' },
+        env: { type: 'string', short: 'e', description: 'comma separated environment variables (format: name=value)', default: '' },
+    },
+    positionals: [
+        { name: 'name', description: 'Name of the profile' },
+    ],
+    longDescription: `
+    ex)
+        profile save --host 10.0.0.5:5654 --user sys --password manager -v /data=/var/data plant1
+        neo-shell --profile plant1
+    `,
+}
+
+const delConfig = {
+    func: doDel,
+    command: 'del',
+    usage: 'profile del <name>',
+    description: 'Delete a connection profile',
+    options: {
+        help: optionHelp,
+    },
+    positionals: [
+        { name: 'name', description: 'Name of the profile to delete' },
+    ],
+}
+
+parseAndRun(process.argv.slice(2), defaultConfig, [
+    listConfig,
+    saveConfig,
+    delConfig,
+]);
+
+function doList(config, args) {
+    try {
+        let box = pretty.Table(config);
+        box.appendHeader(['NAME', 'HOST', 'USER', 'VOLUMES', 'ENV']);
+        for (const name of profile.list()) {
+            const p = profile.load(name);
+            box.append([
+                p.name,
+                p.host,
+                p.user,
+                p.volumes.join(','),
+                Object.keys(p.env).sort().join(','),
+            ]);
+        }
+        console.println(box.render());
+    } catch (err) {
+        console.println('Error:', err.message);
+    }
+}
+
+function splitList(str) {
+    return str.split(',').map(s => s.trim()).filter(s => s.length > 0);
+}
+
+function doSave(config, args) {
+    const obj = {
+        host: config.host || env.get('NEOSHELL_HOST'),
+        user: config.user || env.get('NEOSHELL_USER'),
+        password: config.password || env.get('NEOSHELL_PASSWORD'),
+        volumes: splitList(config.volumes),
+        env: {},
+    };
+    for (const kv of splitList(config.env)) {
+        const idx = kv.indexOf('=');
+        if (idx <= 0) {
+            console.println(`Error: invalid environment variable '${kv}', expected name=value`);
+            return;
+        }
+        obj.env[kv.substring(0, idx)] = kv.substring(idx + 1);
+    }
+    try {
+        profile.save(args.name, obj);
+        console.println(`Profile '${args.name}' saved.`);
+    } catch (err) {
+        console.println('Error saving profile:', err.message);
+    }
+}
+
+function doDel(config, args) {
+    try {
+        profile.remove(args.name);
+        console.println(`Profile '${args.name}' deleted.`);
+    } catch (err) {
+        console.println('Error deleting profile:', err.message);
+    }
+}
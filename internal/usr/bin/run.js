@@ -12,6 +12,11 @@ const zlib = require('zlib');
 const options = {
     help: { type: 'boolean', short: 'h', description: 'Show this help message', default: false },
     verbose: { type: 'boolean', short: 'v', description: 'Enable verbose output', default: false },
+    onError: { type: 'string', description: "action on a failed statement (stop, continue)", default: 'stop' },
+    dryRun: { type: 'boolean', description: "print the statements with the bound parameters without executing", default: false },
+    param: { type: 'string', multiple: true, description: "value of the :name placeholder as name=value, repeatable", default: [] },
+    timeout: { type: 'string', description: "timeout of each statement e.g. 30s (default: $NEOSHELL_QUERY_TIMEOUT)", default: '' },
+    ...pretty.TableArgOptions,
 }
 
 const positionals = [
@@ -46,6 +51,18 @@ if (showHelp || (!filename) || filename.length === 0) {
 }
 
 
+if (config.onError !== 'stop' && config.onError !== 'continue') {
+    console.println(`Invalid --on-error '${config.onError}', it should be stop or continue`);
+    process.exit(1);
+}
+
+// the rows of the statements are printed to the console
+const format = String(config.format).toLowerCase();
+if (format === 'parquet' || format === 'xlsx') {
+    console.println(`Invalid --format '${config.format}', run prints the rows to the console`);
+    process.exit(1);
+}
+
 if (!filename.startsWith("/")) {
     filename = pwd + "/" + filename;
 }
@@ -55,7 +72,10 @@ try {
     const client = new neoapi.Client(config);
     client.splitSqlStatements(content)
         .then((result) => {
-            runSqlStatements(result);
+            const failed = runSqlStatements(result);
+            if (failed > 0) {
+                process.exit(1);
+            }
         })
         .catch((err) => {
             console.println(`Error connecting to server: ${err.message}`);
@@ -66,30 +86,125 @@ try {
     process.exit(1);
 }
 
-
+// runSqlStatements executes the statements on a connection in order and prints the summary,
+// it returns the number of the failed statements.
 function runSqlStatements(statements) {
-    if (!statements || statements.length === 0) {
+    statements = (statements || []).filter(stmt => stmt && !stmt.isComment);
+    if (statements.length === 0) {
         console.println(`No SQL statements found in file '${filename}'.`);
-        return;
+        return 0;
     }
-    const db = new machcli.Client(config);
-    const conn = db.connect();
+    let vars;
     try {
+        vars = machcli.parseParams(config.param);
+    } catch (err) {
+        console.println(err.message);
+        return 1;
+    }
+
+    let db, conn, stop;
+    const results = [];
+    try {
+        if (!config.dryRun) {
+            db = new machcli.Client(config);
+            // Ctrl-C cancels the running statement and skips the rest
+            stop = db.cancelOnInterrupt();
+            conn = db.connect();
+        }
         for (let i = 0; i < statements.length; i++) {
             const stmt = statements[i];
-            if (!stmt || stmt.isComment) {
-                continue;
+            const result = { no: i + 1, lines: `${stmt.beginLine}~${stmt.endLine}`, rows: null, elapsed: 0, error: null };
+            results.push(result);
+            const tick = process.now();
+            try {
+                if (stmt.env && stmt.env.error) {
+                    throw new Error(stmt.env.error);
+                }
+                // the statement ends with ';' that the server does not accept
+                const query = machcli.bindNamed(stmt.text.replace(/;+\s*$/g, ''), vars);
+                if (config.verbose || config.dryRun) {
+                    let sqlText = query.text.split('\n').map(line => line.trim()).filter(line => line.length > 0).join(' ');
+                    console.println(`[${i + 1}/${statements.length}] Line ${result.lines}: ${sqlText}`);
+                    if (query.params.length > 0) {
+                        console.println(`    params: ${query.params.map(p => JSON.stringify(p)).join(', ')}`);
+                    }
+                }
+                if (!config.dryRun) {
+                    result.rows = execute(conn, query);
+                }
+            } catch (err) {
+                result.error = err.message;
+                if (config.dryRun) {
+                    console.println(`    error: ${err.message}`);
+                }
+            }
+            result.elapsed = process.now().unixNano() - tick.unixNano();
+            if (db && db.cancelled()) {
+                result.error = 'query cancelled';
+                break;
             }
-            if (config.verbose) {
-                let sqlText = stmt.text.split('\n').map(line => line.trim()).filter(line => line.length > 0).join(' ');
-                console.println(`\n[${i + 1}/${statements.length}] Line ${stmt.beginLine}~${stmt.endLine}: ${sqlText}`);
+            if (result.error && config.onError === 'stop') {
+                break;
             }
-            process.exec('sql', stmt.text);
         }
     } catch (err) {
-        console.println(`Error executing statements: ${err.message}`);
+        console.println(`Error: ${err.message}`);
+        return 1;
     } finally {
+        stop && stop();
         conn && conn.close();
         db && db.close();
     }
+
+    const failed = results.filter(r => r.error).length;
+    const skipped = statements.length - results.length;
+    if (!config.dryRun) {
+        const box = pretty.Table({ ...config, format: 'box', rownum: false, footer: false, pause: false });
+        box.appendHeader(['#', 'LINES', 'ROWS', 'ELAPSED', 'ERROR']);
+        for (const r of results) {
+            box.append([r.no, r.lines, r.rows === null ? '' : r.rows, pretty.Durations(r.elapsed), r.error || '']);
+        }
+        console.println(box.render());
+    }
+    let summary = `${results.length - failed} ${config.dryRun ? 'checked' : 'succeeded'}, ${failed} failed`;
+    if (skipped > 0) {
+        summary += `, ${skipped} skipped`;
+    }
+    console.println(`${statements.length} statements, ${summary}.`);
+    return failed;
+}
+
+// execute runs the statement, the rows of a statement that returns rows are printed and counted
+// and the plan of EXPLAIN is printed, it returns the number of the rows selected or affected.
+function execute(conn, query) {
+    const explain = /^\s*EXPLAIN(\s+FULL)?\s+/i.exec(query.text);
+    if (explain) {
+        // the server does not explain a prepared statement
+        const plan = String(conn.explain(query.text.substring(explain[0].length), !!explain[1]));
+        console.println(plan);
+        return plan.split('\n').filter(line => line.trim().length > 0).length;
+    }
+    let rows;
+    try {
+        rows = conn.query(query.text, ...query.params);
+        if (!rows.isFetchable()) {
+            return rows.rowsAffected();
+        }
+        const box = pretty.Table({ ...config, pause: false });
+        box.setOutput(console);
+        box.appendHeader(rows.columnNames);
+        box.setColumnTypes(rows.columnTypes);
+        let nRows = 0;
+        for (const row of rows) {
+            nRows++;
+            box.append([...row]);
+            if (box.requirePageRender()) {
+                box.render();
+            }
+        }
+        box.close();
+        return nRows;
+    } finally {
+        rows && rows.close();
+    }
 }
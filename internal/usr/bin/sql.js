@@ -2,7 +2,7 @@
 
 const process = require('process');
 const parseArgs = require('util/parseArgs');
-const { Client } = require('/usr/lib/machcli');
+const { Client, queryTableSchema, createTableSQL, bindNamed, parseParams } = require('/usr/lib/machcli');
 const pretty = require('/usr/lib/pretty');
 
 const options = {
@@ -12,7 +12,11 @@ const options = {
     timing: { type: 'boolean', short: 'T', description: "print elapsed time", default: false },
     showTz: { type: 'boolean', short: 'Z', description: "show time zone in datetime column header", default: false },
     progress: { type: 'integer', description: "the expected maximum progress value (0: unknown, -1: disable)", default: 0 },
+    timeout: { type: 'string', description: "timeout of the statement e.g. 30s (default: $NEOSHELL_QUERY_TIMEOUT)", default: '' },
     ...pretty.TableArgOptions,
+    table: { type: 'string', description: "table name of the insert format", default: '' },
+    param: { type: 'string', multiple: true, description: "value of the :name placeholder as name=value, repeatable", default: [] },
+    createTable: { type: 'boolean', description: "write CREATE TABLE of --table before the insert statements", default: false },
 }
 const positionals = [
     { name: 'sql', type: 'string', variadic: true, description: 'SQL query to explain' }
@@ -46,43 +50,39 @@ if (showHelp || (!args.sql) || args.sql.length === 0) {
 }
 
 const sqlText = args.sql.join(' ');
-let db, conn, rows;
+let db, conn, rows, stop, box, output;
 try {
     db = new Client(config);
+    // Ctrl-C cancels the query and returns to the prompt
+    stop = db.cancelOnInterrupt();
     conn = db.connect();
-    rows = conn.query(sqlText);
+    const query = bindNamed(sqlText, parseParams(config.param));
+    rows = conn.query(query.text, ...query.params);
 
     let tick = process.now();
-    let box = pretty.Table(config);
-    let writer = null;
-    let gzip = null;
+    box = pretty.Table(config);
+    if (String(config.format).toLowerCase() === 'insert') {
+        if (!config.table) {
+            throw new Error('insert format requires --table');
+        }
+        if (config.createTable) {
+            const schema = queryTableSchema(conn, db.normalizeTableName(config.table));
+            if (!schema) {
+                throw new Error(`Table '${config.table}' not found for --create-table`);
+            }
+            box.setPreamble(createTableSQL(config.table, schema));
+        }
+    }
     let nRows = 0;
     let tracker = null;
 
-    if (config.output === '' || config.output === '-') {
-        box.setOutput(console);
-    } else {
-        const fs = require('fs');
-        const path = require('path');
-        const outputPath = path.resolve(config.output);
-        writer = fs.createWriteStream(outputPath, { encoding: 'utf8' });
-        if (config.compress === 'gzip') {
-            const zlib = require('zlib');
-            gzip = zlib.createGzip();
-            gzip.pipe(writer);
-            box.setOutput(gzip);
-        } else {
-            box.setOutput(writer);
-        }
-        if (config.progress >= 0) {
-            let pw = pretty.Progress({ showPercentage: config.progress > 0 });
-            tracker = pw.tracker({
-                total: config.progress,
-                message: `Writing to ${outputPath}`,
-            });
-        }
-        // disable pause for file output
-        box.setPause(false);
+    output = pretty.openOutput(box, config);
+    if (output.path && config.progress >= 0) {
+        let pw = pretty.Progress({ showPercentage: config.progress > 0 });
+        tracker = pw.tracker({
+            total: config.progress,
+            message: `Writing to ${output.path}`,
+        });
     }
 
     if (config.showTz) {
@@ -118,12 +118,7 @@ try {
 
     // render remaining rows
     box.close();
-    if (gzip) {
-        gzip.end();
-    }
-    if (writer) {
-        writer.end();
-    }
+    output.close();
     // footer message
     let footMessage = '';
     if (config.footer) {
@@ -137,8 +132,16 @@ try {
         console.println(footMessage.trim());
     }
 } catch (err) {
-    console.println("Error: ", err.message);
+    // discard the rows not written, e.g. the temporary file of xlsx
+    box && box.abort();
+    output && output.close();
+    if (db && db.cancelled()) {
+        console.println('query cancelled');
+    } else {
+        console.println("Error: ", err.message);
+    }
 } finally {
+    stop && stop();
     rows && rows.close();
     conn && conn.close();
     db && db.close();
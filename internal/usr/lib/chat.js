@@ -1,7 +1,7 @@
 'use strict';
 
 const EventEmitter = require('/lib/events');
-const { getHttpConfig, setHttpToken, getHttpAccessToken, getHttpRefreshToken } = require('@jsh/session');
+const { getHttpConfig, getHttpAccessToken } = require('@jsh/session');
 
 // events: "answer-start", "answer-stop"
 class Chat extends EventEmitter {
@@ -14,38 +14,28 @@ class Chat extends EventEmitter {
     login() {
         console.println(`Logging in to ${this.options.host}:${this.options.port} as ${this.options.user}...`);
         return new Promise((resolve, reject) => {
-            const req = http.request({
-                method: 'POST',
-                protocol: this.options.protocol,
-                host: this.options.host,
-                port: this.options.port,
-                path: '/web/api/login',
-                headers: {
-                    'Content-Type': 'application/json'
-                }
-            });
-            req.on('response', (res) => {
-                const result = res.json();
-                if (!result.success) {
-                    reject(new Error('Login failed: ' + result.reason));
-                    return;
-                }
-                this.accessToken = result.accessToken;
-                this.refreshToken = result.refreshToken;
-                const wsUrl = `${this.options.protocol === 'https:' ? 'wss:' : 'ws:'}//${this.options.host}:${this.options.port}/web/api/console/1234/data?token=${this.accessToken}`;
-                resolve(wsUrl);
-            });
-            req.on('error', (err) => {
-                reject(err);
-            });
-            const body = JSON.stringify({
-                loginName: this.options.user,
-                password: this.options.password
-            });
-            req.write(body);
-            req.end();
+            try {
+                this.accessToken = getHttpAccessToken();
+            } catch (err) {
+                reject(new Error('Login failed: ' + err.message));
+                return;
+            }
+            const wsUrl = `${this.options.protocol === 'https:' ? 'wss:' : 'ws:'}//${this.options.host}:${this.options.port}/web/api/console/1234/data?token=${this.accessToken}`;
+            resolve(wsUrl);
         });
     }
+    // TLS options for WebSocket when the server is https
+    tlsOptions() {
+        if (this.options.protocol !== 'https:') {
+            return {};
+        }
+        return {
+            ca: this.options.ca,
+            cert: this.options.cert,
+            key: this.options.key,
+            rejectUnauthorized: this.options.rejectUnauthorized,
+        };
+    }
     answering(reply) {
         // reply: {data:{"type":"msg","msg":{"body":null,"id":1234,"type":"answer-start","ver":"1.0"}}
         const obj = JSON.parse(reply.data);
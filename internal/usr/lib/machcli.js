@@ -1,11 +1,31 @@
 'use strict';
 
 const _machcli = require('@jsh/machcli');
-const { getMachCliConfig } = require('@jsh/session');
+const _session = require('@jsh/session');
+const process = require('process');
+
+// QUERY_TIMEOUT_ENV is the environment variable of the default timeout of the statements, e.g. 30s.
+const QUERY_TIMEOUT_ENV = 'NEOSHELL_QUERY_TIMEOUT';
 
 class Client {
+    // conf.session can be a session handle from session.open() or a name of session,
+    // the active session is used if it is not specified.
+    // conf.timeout is the timeout of each statement, NEOSHELL_QUERY_TIMEOUT is used if it is not specified.
     constructor(conf) {
-        this.db = _machcli.NewDatabase(JSON.stringify({ ...getMachCliConfig(), ...conf }));
+        const { session, ...rest } = conf || {};
+        if (!rest.timeout) {
+            rest.timeout = process.env.get(QUERY_TIMEOUT_ENV) || '';
+        }
+        const sess = _session.resolve(session);
+        this.session = sess;
+        const base = { ...sess.getMachCliConfig() };
+        if (rest.host || rest.port) {
+            // the failover servers of the session do not apply to the other server
+            delete base.alternativeHost;
+            delete base.alternativePort;
+            delete base.candidates;
+        }
+        this.db = _machcli.NewDatabase(JSON.stringify({ ...base, ...rest }));
         this.ctx = this.db.ctx;
     }
     close() {
@@ -13,34 +33,100 @@ class Client {
     }
     connect() {
         let conn = this.db.connect();
-        return new Connection(this.ctx, conn);
+        return new Connection(this.ctx, conn, this.db);
+    }
+    // cancel cancels the context of the client, the running statements are cancelled on the server
+    // and the next ones fail with 'query cancelled'.
+    cancel() {
+        this.db.cancel();
+    }
+    cancelled() {
+        return this.db.cancelled();
+    }
+    // setTimeout sets the timeout of the statements that start after it, e.g. '30s', '0' is no timeout.
+    setTimeout(timeout) {
+        this.db.setTimeout(String(timeout));
+    }
+    // cancelOnInterrupt cancels the client by SIGINT (Ctrl-C),
+    // it returns the function that stops watching the signal.
+    cancelOnInterrupt() {
+        return this.db.cancelOnInterrupt();
     }
     normalizeTableName(tableName) {
         return this.db.normalizeTableName(tableName);
     }
+    // importParallel starts to import the csv files of the paths by the workers
+    // that have their own connections, options are
+    // { workers, separator, header, timeformat, tz, nullValue, compress, dryRun }.
+    importParallel(tableName, paths, options) {
+        return this.db.importParallel(tableName, paths, options || {});
+    }
     user() {
         return this.db.user();
     }
 }
 
 class Connection {
-    constructor(ctx, dbConn) {
+    constructor(ctx, dbConn, db) {
         this.ctx = ctx;
         this.conn = dbConn;
+        this.db = db;
+    }
+    // statement starts the context of a statement that has the timeout of the client,
+    // the statement is cancelled on the server when the context is done before it is closed.
+    // It throws if the client is cancelled already.
+    statement() {
+        const qctx = this.db.newQueryContext();
+        if (qctx.done()) {
+            const reason = qctx.reason();
+            qctx.close();
+            throw new Error(reason);
+        }
+        this.db.watch(this.conn, qctx);
+        return qctx;
     }
     close() {
         this.conn.close();
+        this.db.forget(this.conn);
     }
     explain() {
-        let plan = this.conn.explain(this.ctx, ...arguments);
-        return plan;
+        const qctx = this.statement();
+        try {
+            let plan = this.conn.explain(qctx.ctx, ...arguments);
+            if (qctx.done()) {
+                throw new Error(qctx.reason());
+            }
+            return plan;
+        } catch (err) {
+            throw stoppedError(qctx, err);
+        } finally {
+            qctx.close();
+        }
     }
+    // query returns the rows of the statement,
+    // the statement is cancelled on the server when it is cancelled or timed out until the rows are closed.
     query() {
-        let rows = this.conn.query(this.ctx, ...arguments);
-        return new Rows(this.ctx, rows);
+        const qctx = this.statement();
+        try {
+            let rows = this.conn.query(qctx.ctx, ...arguments);
+            return new Rows(qctx, rows);
+        } catch (err) {
+            const e = stoppedError(qctx, err);
+            qctx.close();
+            throw e;
+        }
     }
     queryRow() {
-        let row = this.conn.queryRow(this.ctx, ...arguments);
+        const qctx = this.statement();
+        let row;
+        try {
+            row = this.conn.queryRow(qctx.ctx, ...arguments);
+            if (qctx.done()) {
+                throw new Error(qctx.reason());
+            }
+        } finally {
+            qctx.close();
+        }
         let value = { _ROWNUM: 1 };
         value.err = () => { row.err(); };
         if (row.err()) {
@@ -56,7 +142,17 @@ class Connection {
         return value;
     }
     exec() {
-        let result = this.conn.exec(this.ctx, ...arguments);
+        const qctx = this.statement();
+        let result;
+        try {
+            result = this.conn.exec(qctx.ctx, ...arguments);
+            // the statement that is done after the deadline fails as well
+            if (qctx.done()) {
+                throw new Error(qctx.reason());
+            }
+        } finally {
+            qctx.close();
+        }
         if (result.err()) {
             throw new Error(result.err());
         }
@@ -71,22 +167,65 @@ class Connection {
     }
 }
 
+// stoppedError returns the error of the statement that failed,
+// it is the reason if the statement is cancelled or timed out.
+function stoppedError(qctx, err) {
+    return qctx.done() ? new Error(qctx.reason()) : err;
+}
+
+const unsignedTypes = { ushort: 'uint16', uinteger: 'uint32', ulong: 'uint64' };
+
 class Rows {
-    constructor(ctx, dbRows) {
-        this.ctx = ctx;
+    constructor(qctx, dbRows) {
+        this.qctx = qctx;
+        this.ctx = qctx.ctx;
         this.rows = dbRows;
         this.cols = dbRows.columns();
         this.columnNames = this.cols.names();
-        this.columnTypes = this.cols.dataTypes();
+        // json columns are strings of the data type, keep them distinguished for the json output,
+        // and the unsigned columns from the signed ones of the same size, e.g. for parquet
+        this.columnTypes = [...this.cols.dataTypes()].map((t, i) => {
+            const type = String(this.cols[i].type);
+            if (type === 'json') {
+                return 'json';
+            }
+            return unsignedTypes[type] || String(t);
+        });
         this.rownum = 0;
         this.message = dbRows.message();
+        this.finished = false;
     }
     close() {
         this.rows.close();
+        this.qctx.close();
+    }
+    // elapsed returns the elapsed time of the statement in nanoseconds.
+    elapsed() {
+        return this.qctx.elapsed();
+    }
+    // isFetchable returns true if the statement returns rows, e.g. SELECT,
+    // the other statements have only the number of the affected rows.
+    isFetchable() {
+        return this.rows.isFetchable();
+    }
+    rowsAffected() {
+        return this.rows.rowsAffected();
     }
     next() {
-        let hasNext = this.rows.next(this.ctx);
+        if (this.finished) {
+            return { done: true };
+        }
+        if (this.qctx.done()) {
+            throw new Error(this.qctx.reason());
+        }
+        let hasNext = this.rows.next();
         if (!hasNext) {
+            if (this.qctx.done()) {
+                throw new Error(this.qctx.reason());
+            }
+            // all rows are fetched, the statement is not cancelled after it
+            this.finished = true;
+            this.qctx.close();
             return { done: true };
         }
         let buffer = this.cols.makeBuffer();
@@ -326,8 +465,163 @@ function stringColumnFlag(flag) {
     return flags.join(",");
 }
 
+/**
+ * Returns the schema of the table from M$SYS_TABLES and M$SYS_COLUMNS,
+ * or null if the table does not exist. The hidden columns that start with '_' are excluded.
+ * @param {Connection} conn
+ * @param {string[]} names - [database, user, table] from normalizeTableName()
+ * @returns {{type: number, flag: number, columns: {name: string, type: number, length: number, flag: number}[]}}
+ */
+function queryTableSchema(conn, names) {
+    const dbId = queryDatabaseId(conn, names[0]);
+    let row;
+    try {
+        row = conn.queryRow(`SELECT j.ID as TABLE_ID, j.TYPE as TABLE_TYPE, j.FLAG as TABLE_FLAG
+            FROM M$SYS_USERS u, M$SYS_TABLES j
+            WHERE u.NAME = ? AND j.USER_ID = u.USER_ID AND j.DATABASE_ID = ? AND j.NAME = ?`,
+            names[1], dbId, names[2]);
+    } catch {
+        row = null;
+    }
+    if (!row || !row.TABLE_ID) {
+        return null;
+    }
+    const schema = { type: row.TABLE_TYPE, flag: row.TABLE_FLAG, columns: [] };
+    let rows;
+    try {
+        rows = conn.query(`SELECT NAME, TYPE, LENGTH, FLAG FROM M$SYS_COLUMNS WHERE TABLE_ID = ? AND DATABASE_ID = ? ORDER BY ID`,
+            row.TABLE_ID, dbId);
+        for (const col of rows) {
+            if (col.NAME.startsWith('_')) {
+                continue;
+            }
+            schema.columns.push({ name: col.NAME, type: col.TYPE, length: col.LENGTH, flag: col.FLAG });
+        }
+    } finally {
+        rows && rows.close();
+    }
+    return schema;
+}
+
+function columnTypeSQL(colType, length) {
+    const name = stringColumnType(colType);
+    if (colType === ColumnType.Varchar) {
+        return `${name}(${length})`;
+    }
+    return name;
+}
+
+/**
+ * Builds the CREATE TABLE statement of the schema from queryTableSchema().
+ * @param {string} tableName
+ * @param {object} schema
+ * @returns {string}
+ */
+function createTableSQL(tableName, schema) {
+    let kind;
+    switch (schema.type) {
+        case TableType.Log:
+            kind = '';
+            break;
+        case TableType.Tag:
+            kind = 'TAG ';
+            break;
+        case TableType.Volatile:
+            kind = 'VOLATILE ';
+            break;
+        case TableType.Lookup:
+            kind = 'LOOKUP ';
+            break;
+        default:
+            throw new Error(`${stringTableType(schema.type)} table is not supported`);
+    }
+    const cols = [];
+    const metaCols = [];
+    for (const col of schema.columns) {
+        let def = `${col.name} ${columnTypeSQL(col.type, col.length)}`;
+        if (col.flag & ColumnFlag.TagName) {
+            def += ' primary key';
+        }
+        if (col.flag & ColumnFlag.Basetime) {
+            def += ' basetime';
+        }
+        if (col.flag & ColumnFlag.Summarized) {
+            def += ' summarized';
+        }
+        if (col.flag & ColumnFlag.MetaColumn) {
+            metaCols.push(def);
+        } else {
+            cols.push(def);
+        }
+    }
+    let sqlText = `CREATE ${kind}TABLE ${tableName} (${cols.join(', ')})`;
+    if (metaCols.length > 0) {
+        sqlText += ` METADATA (${metaCols.join(', ')})`;
+    }
+    return sqlText;
+}
+
+// openRecordReader opens a parquet or arrow IPC file to import,
+// reader.bind(appender) maps the columns of the file to the table by name.
+function openRecordReader(format, path) {
+    return _machcli.NewRecordReader(format, path);
+}
+
+// openNDJSONReader opens a NDJSON file to import, '-' for stdin,
+// options are { timeformat, tz, compress, dryRun, resumeFromLine }.
+function openNDJSONReader(path, options) {
+    return _machcli.NewNDJSONReader(path, options || {});
+}
+
+// openCSVReader opens a csv or tsv file to import, '-' for stdin, options are
+// { separator, header, timeformat, tz, nullValue, compress, dryRun, resumeFromLine }.
+function openCSVReader(path, options) {
+    return _machcli.NewCSVReader(path, options || {});
+}
+
+// newConverter returns the converter of the text fields to the values of the columns,
+// columns are the ones of appender.columns() in the order of the fields,
+// options are { timeformat, tz, nullValue }.
+function newConverter(columns, options) {
+    return _machcli.NewConverter(columns, options || {});
+}
+
+// bindNamed replaces the :name placeholders of the statement with '?',
+// it returns { text, params } of the values of vars in the order of the placeholders.
+function bindNamed(sqlText, vars) {
+    return _machcli.BindNamed(sqlText, vars || {});
+}
+
+// parseParams returns the variables of the 'name=value' list, e.g. of --param.
+function parseParams(list) {
+    const vars = {};
+    for (const p of [].concat(list || [])) {
+        const idx = p.indexOf('=');
+        if (idx <= 0) {
+            throw new Error(`invalid parameter '${p}', it should be name=value`);
+        }
+        vars[p.substring(0, idx).trim()] = p.substring(idx + 1);
+    }
+    return vars;
+}
+
+// scanMigrations returns the migrations of V<version>__<name>.sql files in the directory
+// in the order of the versions, { version, name, path, checksum, text }.
+function scanMigrations(dir) {
+    return _machcli.ScanMigrations(dir);
+}
+
 module.exports = {
     Client,
+    openRecordReader,
+    openNDJSONReader,
+    openCSVReader,
+    newConverter,
+    bindNamed,
+    parseParams,
+    scanMigrations,
+    queryTableSchema,
+    createTableSQL,
     queryDatabaseId,
     queryTableType,
     stringTableType,
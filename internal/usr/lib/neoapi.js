@@ -1,111 +1,84 @@
 'use strict';
 
 const http = require('http');
-const { getHttpConfig, setHttpToken, getHttpAccessToken, getHttpRefreshToken } = require('@jsh/session');
+const _session = require('@jsh/session');
 
 class _Client {
+    // options.session can be a session handle from session.open() or a name of session,
+    // the active session is used if it is not specified.
     constructor(options = {}) {
-        this.options = { ...getHttpConfig(), ...options }
+        const { session, ...rest } = options;
+        this.session = _session.resolve(session);
+        this.options = { ...this.session.getHttpConfig(), ...rest }
     }
+    /**
+     * Builds the options of http.request() for POST to the path,
+     * including TLS options when the server is https.
+     */
+    _requestOptions(path, headers) {
+        const opts = {
+            method: 'POST',
+            protocol: this.options.protocol,
+            host: this.options.host,
+            port: this.options.port,
+            path: path,
+            headers: headers,
+        };
+        if (this.options.protocol === 'https:') {
+            opts.ca = this.options.ca;
+            opts.cert = this.options.cert;
+            opts.key = this.options.key;
+            opts.rejectUnauthorized = this.options.rejectUnauthorized;
+        }
+        return opts;
+    }
+    /**
+     * Makes sure the session has a valid access token.
+     * The session logs in and refreshes the token before it expires.
+     * @returns {Promise}
+     */
     login() {
         return new Promise((resolve, reject) => {
-            const req = http.request({
-                method: 'POST',
-                protocol: this.options.protocol,
-                host: this.options.host,
-                port: this.options.port,
-                path: '/web/api/login',
-                headers: {
-                    'Content-Type': 'application/json'
-                }
-            });
-            req.on('response', (res) => {
-                const result = res.json();
-                if (!result.success) {
-                    reject(new Error('Login failed: ' + result.reason));
-                    return;
-                }
-                setHttpToken(result.accessToken, result.refreshToken);
-                resolve(result.reason);
-            });
-            req.on('error', (err) => {
-                reject(err);
-            });
-            const body = JSON.stringify({
-                loginName: this.options.user,
-                password: this.options.password
-            });
-            req.write(body);
-            req.end();
+            try {
+                this.session.getHttpAccessToken();
+                resolve();
+            } catch (err) {
+                reject(new Error('Login failed: ' + err.message));
+            }
         });
     }
-    relogin() {
+    /**
+     * Refreshes the access token that is rejected by the server.
+     * @param {string} rejected - The rejected access token
+     * @returns {Promise}
+     */
+    relogin(rejected) {
         return new Promise((resolve, reject) => {
-            if (!getHttpRefreshToken()) {
-                reject(new Error('No refresh token available'));
-                return;
+            try {
+                this.session.refreshHttpToken(rejected);
+                resolve();
+            } catch (err) {
+                reject(new Error('Relogin failed: ' + err.message));
             }
-            const req = http.request({
-                method: 'POST',
-                protocol: this.options.protocol,
-                host: this.options.host,
-                port: this.options.port,
-                path: '/web/api/relogin',
-                headers: {
-                    'Content-Type': 'application/json'
-                }
-            });
-            req.on('response', (res) => {
-                const result = res.json();
-                if (!result.success) {
-                    reject(new Error('Relogin failed: ' + result.reason));
-                    return;
-                }
-                setHttpToken(result.accessToken, result.refreshToken);
-                resolve(result.reason);
-            });
-            req.on('error', (err) => {
-                reject(err);
-            });
-            const body = JSON.stringify({
-                refreshToken: getHttpRefreshToken()
-            });
-            req.write(body);
-            req.end();
         });
     }
 
     /**
      * Executes an authenticated request.
-     * Automatically handles login and relogin on 401 errors.
+     * Automatically refreshes the token and retries on 401 errors.
      * @param {Function} requestFn - Function that executes the request (must return a Promise)
      * @returns {Promise} Request result
      */
     _executeWithAuth(requestFn) {
-        const executeRequest = () => {
-            return requestFn().catch((err) => {
-                // If 401 error, attempt relogin and retry request
-                if (err.unauthorized) {
-                    return this.relogin().then(() => {
-                        return requestFn();
-                    }).catch((err) => {
-                        throw err;
-                    });
-                }
-                throw err;
-            });
-        };
-
-        // Attempt login if accessToken is not available
-        if (!getHttpAccessToken()) {
-            return this.login().then(() => {
-                return executeRequest();
-            }).catch((err) => {
-                return Promise.reject(err);
-            });
-        }
-
-        return executeRequest();
+        return requestFn().catch((err) => {
+            // If 401 error, refresh the token and retry request
+            if (err.unauthorized) {
+                return this.relogin(err.token).then(() => {
+                    return requestFn();
+                });
+            }
+            throw err;
+        });
     }
 
     /**
@@ -116,21 +89,15 @@ class _Client {
      */
     _rpcRequest(method, params = []) {
         return new Promise((resolve, reject) => {
-            const req = http.request({
-                method: 'POST',
-                protocol: this.options.protocol,
-                host: this.options.host,
-                port: this.options.port,
-                path: '/web/api/rpc',
-                headers: {
-                    'Content-Type': 'application/json',
-                    'Authorization': `Bearer ${getHttpAccessToken()}`
-                }
-            });
+            const token = this.session.getHttpAccessToken();
+            const req = http.request(this._requestOptions('/web/api/rpc', {
+                'Content-Type': 'application/json',
+                'Authorization': `Bearer ${token}`
+            }));
             req.on('response', (res) => {
                 // Mark rejection with special flag if 401 error
                 if (res.statusCode === 401) {
-                    reject({ unauthorized: true });
+                    reject({ unauthorized: true, token: token });
                     return;
                 }
                 if (res.statusCode < 200 || res.statusCode >= 300) {
@@ -159,17 +126,19 @@ class _Client {
     }
 }
 
-let _instance = null;
+// one instance per session
+const _instances = {};
 
 class Client extends _Client {
     constructor(options = {}) {
-        // Return existing instance if already created
-        if (_instance) {
-            return _instance;
+        const name = _session.resolve(options.session).name();
+        // Return existing instance of the session if already created
+        if (_instances[name]) {
+            return _instances[name];
         }
 
         super(options);
-        _instance = this;
+        _instances[name] = this;
     }
 
     markdownRender(mdText, darkMode = false) {
@@ -13,6 +13,7 @@ const defaultTableConfig = {
     rownum: true,
     nullValue: 'NULL',
     stringEscape: false,
+    blobEncoding: 'base64',
 }
 
 function Table(config) {
@@ -27,7 +28,7 @@ function Table(config) {
 }
 
 const TableArgOptions = {
-    format: { type: 'string', short: 'f', description: "output format (box, csv, tsv, json, ndjson)", default: 'box' },
+    format: { type: 'string', short: 'f', description: "output format (box, csv, tsv, json, ndjson, yaml, xml, parquet, xlsx, insert)", default: 'box' },
     boxStyle: { type: 'string', description: "box style (simple, bold, double, light, round, colored-bright, colored-dark)", default: 'light' },
     rownum: { type: 'boolean', description: "show row numbers", default: true },
     timeformat: { type: 'string', short: 't', description: "time format [ns|us|ms|s|<timeformat>]", default: 'default' },
@@ -37,6 +38,46 @@ const TableArgOptions = {
     footer: { type: 'boolean', description: "print footer", default: true },
     pause: { type: 'boolean', description: "pause for the screen paging", default: true },
     nullValue: { type: 'string', description: "string to represent null values", default: 'NULL' },
+    blobEncoding: { type: 'string', description: "encoding of binary values in json, ndjson (base64, hex)", default: 'base64' },
+}
+
+/**
+ * Sets the output of the box to the file of config.output, or to the console if it is '' or '-'.
+ * The file is compressed when config.compress is 'gzip'.
+ * @returns {{path: string|null, close: function}} path is null for the console,
+ *   close() ends the file after the box is closed.
+ */
+function openOutput(box, config) {
+    if (!config.output || config.output === '-') {
+        const format = String(config.format).toLowerCase();
+        if (format === 'parquet' || format === 'xlsx') {
+            throw new Error(`${format} format requires --output`);
+        }
+        box.setOutput(console);
+        return { path: null, close() { } };
+    }
+    const fs = require('fs');
+    const path = require('path');
+    const outputPath = path.resolve(config.output);
+    const writer = fs.createWriteStream(outputPath, { encoding: 'utf8' });
+    let gzip = null;
+    if (config.compress === 'gzip') {
+        const zlib = require('zlib');
+        gzip = zlib.createGzip();
+        gzip.pipe(writer);
+        box.setOutput(gzip);
+    } else {
+        box.setOutput(writer);
+    }
+    // disable pause for file output
+    box.setPause(false);
+    return {
+        path: outputPath,
+        close() {
+            gzip && gzip.end();
+            writer.end();
+        },
+    };
 }
 
 const Align = {
@@ -51,5 +92,6 @@ module.exports = {
     ..._pretty,
     Table,
     TableArgOptions,
+    openOutput,
     Align,
 }
\ No newline at end of file
@@ -0,0 +1,140 @@
+// Package vfs opens the files of the virtual file system that the scripts see,
+// so that the native modules can read the files by the paths of the scripts.
+package vfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/machbase/jsh/engine"
+)
+
+type mount struct {
+	point string
+	fsys  fs.FS
+}
+
+var (
+	mu     sync.RWMutex
+	mounts []mount
+)
+
+// Mount replaces the mount table with the tabs,
+// a path is resolved by the longest mount point that contains it.
+func Mount(tabs engine.FSTabs) {
+	ms := []mount{}
+	for _, tab := range tabs {
+		fsys := tab.FS
+		if fsys == nil && tab.Source != "" {
+			fsys = os.DirFS(tab.Source)
+		}
+		if fsys == nil {
+			continue
+		}
+		ms = append(ms, mount{point: path.Clean("/" + tab.MountPoint), fsys: fsys})
+	}
+	slices.SortStableFunc(ms, func(a, b mount) int { return len(b.point) - len(a.point) })
+	mu.Lock()
+	mounts = ms
+	mu.Unlock()
+}
+
+// Open opens the file of the absolute virtual path.
+func Open(name string) (fs.File, error) {
+	if !path.IsAbs(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("not an absolute path")}
+	}
+	name = path.Clean(name)
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, m := range mounts {
+		if rel, ok := within(name, m.point); ok {
+			return m.fsys.Open(rel)
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// within returns the path relative to the mount point, if the name is in the mount point.
+func within(name string, point string) (string, bool) {
+	if point == "/" {
+		if name == "/" {
+			return ".", true
+		}
+		return name[1:], true
+	}
+	if name == point {
+		return ".", true
+	}
+	if rest, ok := strings.CutPrefix(name, point+"/"); ok {
+		return rest, true
+	}
+	return "", false
+}
+
+// File is a file that supports random access.
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+}
+
+// OpenFile opens the file of the virtual path for random access and returns its size,
+// the file is read into memory if the file system does not support random access.
+func OpenFile(name string) (File, int64, error) {
+	f, err := Open(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	if rf, ok := f.(File); ok {
+		if st, err := f.Stat(); err == nil {
+			return rf, st.Size(), nil
+		}
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, 0, err
+	}
+	return memFile{bytes.NewReader(b)}, int64(len(b)), nil
+}
+
+type memFile struct {
+	*bytes.Reader
+}
+
+func (memFile) Close() error { return nil }
+
+// Glob returns the virtual paths of the files that match the absolute pattern,
+// the pattern is matched in the file system of the mount point that contains it.
+func Glob(pattern string) ([]string, error) {
+	if !path.IsAbs(pattern) {
+		return nil, fmt.Errorf("%s: not an absolute path", pattern)
+	}
+	pattern = path.Clean(pattern)
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, m := range mounts {
+		rel, ok := within(pattern, m.point)
+		if !ok {
+			continue
+		}
+		matches, err := fs.Glob(m.fsys, rel)
+		if err != nil {
+			return nil, err
+		}
+		for i, match := range matches {
+			matches[i] = path.Join(m.point, match)
+		}
+		return matches, nil
+	}
+	return nil, nil
+}
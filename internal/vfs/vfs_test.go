@@ -0,0 +1,56 @@
+package vfs
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/machbase/jsh/engine"
+)
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "data.csv"), []byte("a,b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	Mount(engine.FSTabs{
+		{MountPoint: "/", FS: fstest.MapFS{"etc/hello": {Data: []byte("root")}}},
+		{MountPoint: "/work", Source: dir},
+		{MountPoint: "/work/mem", FS: fstest.MapFS{"x.txt": {Data: []byte("mem")}}},
+	})
+	defer Mount(nil)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"/etc/hello", "root"},
+		{"/work/data.csv", "a,b\n"},
+		{"/work/./mem/../data.csv", "a,b\n"},
+		{"/work/mem/x.txt", "mem"},
+	}
+	for _, tt := range tests {
+		f, size, err := OpenFile(tt.name)
+		if err != nil {
+			t.Errorf("OpenFile(%q) failed: %v", tt.name, err)
+			continue
+		}
+		b, _ := io.ReadAll(f)
+		f.Close()
+		if string(b) != tt.want || size != int64(len(tt.want)) {
+			t.Errorf("OpenFile(%q) = %q (%d), want %q", tt.name, b, size, tt.want)
+		}
+	}
+
+	if _, err := Open("/work/none.csv"); err == nil {
+		t.Errorf("expected error for the missing file")
+	}
+	if _, err := Open("data.csv"); err == nil {
+		t.Errorf("expected error for the relative path")
+	}
+	if matches, err := Glob("/work/*.csv"); err != nil || len(matches) != 1 || matches[0] != "/work/data.csv" {
+		t.Errorf("Glob() = %v %v, want [/work/data.csv]", matches, err)
+	}
+}